@@ -0,0 +1,248 @@
+// Package goldengate runs Golden Gate in-process, for Go tests that send
+// traffic through the proxy and inspect what it captured:
+//
+//	gg := goldengate.New(t, goldengate.Config{
+//		Services: []goldengate.Service{
+//			{Name: "buda", Prefix: "/buda", Target: upstream.URL},
+//		},
+//	})
+//	client := buda.NewClient(gg.ServiceURL("buda"))
+//	...
+//	req, err := gg.WaitFor(5*time.Second, goldengate.MustFilter("service=buda method=post status=2xx"))
+package goldengate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/config"
+	"github.com/mtavano/golden-gate/internal/filter"
+	"github.com/mtavano/golden-gate/internal/server"
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+// DefaultMaxRequests is the number of captured requests kept when
+// Config.MaxRequests is zero.
+const DefaultMaxRequests = 1000
+
+// shutdownTimeout bounds how long Close waits for in-flight requests.
+const shutdownTimeout = 5 * time.Second
+
+// Request is a captured exchange, the same as in capture files and the admin
+// API. Pending is set while the upstream has not answered yet.
+type Request = types.RequestLog
+
+// Response is the upstream response of a captured Request.
+type Response = types.ResponseLog
+
+// ContractViolation is a difference between an exchange and the OpenAPI
+// document of its service.
+type ContractViolation = types.ContractViolation
+
+// Config describes an instance.
+type Config struct {
+	Services []Service
+	// MaxRequests is the number of captured requests kept, oldest dropped
+	// first. DefaultMaxRequests when zero.
+	MaxRequests int
+	// Dashboard serves the dashboard at /dashboard.
+	Dashboard bool
+}
+
+// Service proxies the requests under Prefix to Target, like a service in the
+// config file.
+type Service struct {
+	Name string
+	// Prefix is stripped from the incoming path, e.g. "/buda".
+	Prefix string
+	// Target is the upstream base URL.
+	Target string
+	// OpenAPI is the path of an OpenAPI 3 document traffic is validated
+	// against, relative to the working directory.
+	OpenAPI string
+}
+
+// Instance is a running Golden Gate.
+type Instance struct {
+	// URL is the base URL of the instance, like "http://127.0.0.1:41567".
+	URL string
+
+	config *config.Config
+	store  *types.RequestStore
+	cancel context.CancelFunc
+	done   chan error
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Start runs an instance on a free loopback port. Close must be called to
+// stop it.
+func Start(cfg Config) (*Instance, error) {
+	services := make(map[string]config.ServiceConfig, len(cfg.Services))
+	for _, svc := range cfg.Services {
+		if _, ok := services[svc.Name]; ok {
+			return nil, fmt.Errorf("service %q is defined twice", svc.Name)
+		}
+		services[svc.Name] = config.ServiceConfig{
+			BasePrefix: svc.Prefix,
+			Target:     svc.Target,
+			OpenAPI:    svc.OpenAPI,
+		}
+	}
+	gatewayConfig, err := config.New(services)
+	if err != nil {
+		return nil, err
+	}
+	gatewayConfig.Dashboard.Enabled = &cfg.Dashboard
+	gatewayConfig.Store.MaxRequests = cfg.MaxRequests
+	if gatewayConfig.Store.MaxRequests == 0 {
+		gatewayConfig.Store.MaxRequests = DefaultMaxRequests
+	}
+
+	store := types.NewRequestStore(gatewayConfig.Store.MaxRequests)
+	srv, err := server.New("", gatewayConfig, store)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	instance := &Instance{
+		URL:    "http://" + listener.Addr().String(),
+		config: gatewayConfig,
+		store:  store,
+		cancel: cancel,
+		done:   make(chan error, 1),
+	}
+	go func() {
+		instance.done <- srv.Serve(ctx, listener, shutdownTimeout)
+	}()
+	return instance, nil
+}
+
+// New is Start for tests: it fails t if the instance cannot start, and
+// closes it when the test ends.
+func New(t testing.TB, cfg Config) *Instance {
+	t.Helper()
+	instance, err := Start(cfg)
+	if err != nil {
+		t.Fatalf("starting golden gate: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := instance.Close(); err != nil {
+			t.Errorf("closing golden gate: %v", err)
+		}
+	})
+	return instance
+}
+
+// Close stops the instance, waiting for in-flight requests. It may be called
+// more than once.
+func (i *Instance) Close() error {
+	i.closeOnce.Do(func() {
+		i.cancel()
+		i.closeErr = <-i.done
+	})
+	return i.closeErr
+}
+
+// ServiceURL returns the URL clients of the named service should use in
+// place of the upstream, e.g. URL + "/buda". It is empty for unknown
+// services.
+func (i *Instance) ServiceURL(name string) string {
+	svc, ok := i.config.Services[name]
+	if !ok {
+		return ""
+	}
+	return i.URL + strings.TrimSuffix(svc.BasePrefix, "/")
+}
+
+// Requests returns the captured requests, oldest first. They must not be
+// modified.
+func (i *Instance) Requests() []*Request {
+	return i.store.GetRequests()
+}
+
+// Filter returns a match func for the terminal UI filter syntax, like
+// "service=buda method=post status=5xx q=insufficient".
+func Filter(terms string) (func(*Request) bool, error) {
+	f, err := filter.ParseTerms(terms)
+	if err != nil {
+		return nil, err
+	}
+	return f.Match, nil
+}
+
+// MustFilter is Filter for terms known to be valid; it panics otherwise.
+func MustFilter(terms string) func(*Request) bool {
+	match, err := Filter(terms)
+	if err != nil {
+		panic(fmt.Sprintf("goldengate: filter %q: %v", terms, err))
+	}
+	return match
+}
+
+// Wait returns the first completed request, captured already or in the
+// future, that match accepts. It gives up when ctx is done.
+func (i *Instance) Wait(ctx context.Context, match func(*Request) bool) (*Request, error) {
+	// Subscribe before looking at the store so nothing falls in between
+	events, unsubscribe := i.store.Subscribe(256)
+	defer unsubscribe()
+
+	if req := i.find(match); req != nil {
+		return req, nil
+	}
+	var dropped uint64
+	for {
+		select {
+		case <-ctx.Done():
+			// The matching event may have been dropped under load
+			if req := i.find(match); req != nil {
+				return req, nil
+			}
+			return nil, ctx.Err()
+		case event := <-events:
+			if !event.Request.Pending && match(event.Request) {
+				return event.Request, nil
+			}
+			if n := i.store.Dropped(events); n != dropped && len(events) == 0 {
+				dropped = n
+				if req := i.find(match); req != nil {
+					return req, nil
+				}
+			}
+		}
+	}
+}
+
+// find returns the first stored completed request that match accepts.
+func (i *Instance) find(match func(*Request) bool) *Request {
+	for _, req := range i.store.GetRequests() {
+		if !req.Pending && match(req) {
+			return req
+		}
+	}
+	return nil
+}
+
+// WaitFor is Wait with a timeout.
+func (i *Instance) WaitFor(timeout time.Duration, match func(*Request) bool) (*Request, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := i.Wait(ctx, match)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("no matching request captured within %s", timeout)
+	}
+	return req, err
+}
@@ -0,0 +1,129 @@
+package goldengate_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtavano/golden-gate/goldengate"
+)
+
+func upstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		io.WriteString(w, `{"path":"`+r.URL.Path+`"}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWaitFor(t *testing.T) {
+	up := upstream(t)
+	gg := goldengate.New(t, goldengate.Config{
+		Services: []goldengate.Service{{Name: "orders", Prefix: "/orders", Target: up.URL + "/api"}},
+	})
+
+	resp, err := http.Post(gg.ServiceURL("orders")+"/42", "application/json", strings.NewReader(`{"qty":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || string(body) != `{"path":"/api/42"}` {
+		t.Fatalf("proxied response = %d %s", resp.StatusCode, body)
+	}
+
+	req, err := gg.WaitFor(5*time.Second, goldengate.MustFilter("service=orders method=post status=2xx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(req.Body) != `{"qty":1}` || req.Response.StatusCode != http.StatusCreated || string(req.Response.Body) != string(body) {
+		t.Errorf("captured %s %s -> %d %s", req.Method, req.Body, req.Response.StatusCode, req.Response.Body)
+	}
+	if n := len(gg.Requests()); n != 1 {
+		t.Errorf("Requests() has %d captures, want 1", n)
+	}
+}
+
+func TestWaitForLaterRequest(t *testing.T) {
+	up := upstream(t)
+	gg := goldengate.New(t, goldengate.Config{
+		Services: []goldengate.Service{{Name: "orders", Prefix: "/orders", Target: up.URL}},
+	})
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		for _, path := range []string{"/1", "/2"} {
+			if resp, err := http.Get(gg.ServiceURL("orders") + path); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}()
+
+	req, err := gg.WaitFor(5*time.Second, func(req *goldengate.Request) bool {
+		return strings.HasSuffix(req.URL, "/2")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Pending || req.Response == nil {
+		t.Errorf("WaitFor returned a pending capture")
+	}
+}
+
+func TestWaitForTimeout(t *testing.T) {
+	gg := goldengate.New(t, goldengate.Config{
+		Services: []goldengate.Service{{Name: "orders", Prefix: "/orders", Target: upstream(t).URL}},
+	})
+
+	_, err := gg.WaitFor(50*time.Millisecond, goldengate.MustFilter("status=5xx"))
+	if err == nil || !strings.Contains(err.Error(), "no matching request captured within 50ms") {
+		t.Errorf("WaitFor = %v, want a timeout error", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	gg, err := goldengate.Start(goldengate.Config{
+		Services: []goldengate.Service{{Name: "orders", Prefix: "/orders", Target: upstream(t).URL}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gg.Close(); err != nil {
+		t.Fatalf("Close = %v", err)
+	}
+	if err := gg.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+	if resp, err := http.Get(gg.ServiceURL("orders")); err == nil {
+		resp.Body.Close()
+		t.Errorf("the instance still answers after Close")
+	}
+}
+
+func TestStartInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		services []goldengate.Service
+	}{
+		{"duplicate name", []goldengate.Service{
+			{Name: "a", Prefix: "/a", Target: "http://localhost:1"},
+			{Name: "a", Prefix: "/b", Target: "http://localhost:1"},
+		}},
+		{"relative target", []goldengate.Service{{Name: "a", Prefix: "/a", Target: "localhost"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gg, err := goldengate.Start(goldengate.Config{Services: tt.services}); err == nil {
+				gg.Close()
+				t.Errorf("Start succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/mtavano/golden-gate/internal/auth"
+	"github.com/mtavano/golden-gate/internal/capture"
+	"github.com/mtavano/golden-gate/internal/config"
+	"github.com/mtavano/golden-gate/internal/expect"
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+// maxImportSize bounds the body of a capture import.
+const maxImportSize = 256 << 20
+
+var (
+	errNotFound = errors.New("service not found")
+	errExists   = errors.New("service already exists")
+)
+
+// ServiceManager gives access to the active configuration and applies
+// changes to it.
+type ServiceManager interface {
+	Config() *config.Config
+	Update(fn func(cfg *config.Config) error) error
+}
+
+// Service is the API representation of a configured service.
+type Service struct {
+	Name string `json:"name"`
+	config.ServiceConfig
+}
+
+type Handler struct {
+	services     ServiceManager
+	requestStore *types.RequestStore
+	expectations *expect.Registry
+}
+
+func NewHandler(services ServiceManager, requestStore *types.RequestStore) *Handler {
+	return &Handler{
+		services:     services,
+		requestStore: requestStore,
+		expectations: expect.NewRegistry(),
+	}
+}
+
+// Register mounts the admin endpoints on r. Reading services needs the
+// viewer role, captures and expectations need the operator role, and changes
+// need the admin role. The API never lets anonymous callers in: without
+// configured authentication it only accepts GOLDEN_GATE_ADMIN_TOKEN, and is
+// disabled when that is unset.
+func (h *Handler) Register(r *mux.Router, authn *auth.Authenticator) {
+	require := func(role auth.Role, next http.HandlerFunc) http.Handler {
+		return authn.Require(role, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if auth.FromContext(r.Context()).Anonymous {
+				if !authn.HasTokens() {
+					writeError(w, http.StatusNotFound, errors.New("admin API is disabled"))
+					return
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="golden-gate"`)
+				writeError(w, http.StatusUnauthorized, errors.New("invalid or missing bearer token"))
+				return
+			}
+			next(w, r)
+		}))
+	}
+
+	r.Handle("/services", require(auth.RoleViewer, h.listServices)).Methods(http.MethodGet)
+	r.Handle("/services", require(auth.RoleAdmin, h.createService)).Methods(http.MethodPost)
+	r.Handle("/services/{name}", require(auth.RoleViewer, h.getService)).Methods(http.MethodGet)
+	r.Handle("/services/{name}", require(auth.RoleAdmin, h.updateService)).Methods(http.MethodPut)
+	r.Handle("/services/{name}", require(auth.RoleAdmin, h.deleteService)).Methods(http.MethodDelete)
+	r.Handle("/services/{name}/disable", require(auth.RoleAdmin, h.setDisabled(true))).Methods(http.MethodPost)
+	r.Handle("/services/{name}/enable", require(auth.RoleAdmin, h.setDisabled(false))).Methods(http.MethodPost)
+	r.Handle("/captures", require(auth.RoleOperator, h.exportCaptures)).Methods(http.MethodGet)
+	r.Handle("/captures", require(auth.RoleOperator, h.importCaptures)).Methods(http.MethodPost)
+	r.Handle("/captures/stream", require(auth.RoleOperator, h.streamCaptures)).Methods(http.MethodGet)
+	r.Handle("/expectations", require(auth.RoleOperator, h.listExpectations)).Methods(http.MethodGet)
+	r.Handle("/expectations", require(auth.RoleOperator, h.createExpectations)).Methods(http.MethodPost)
+	r.Handle("/expectations/{id}", require(auth.RoleOperator, h.getExpectations)).Methods(http.MethodGet)
+	r.Handle("/expectations/{id}", require(auth.RoleOperator, h.deleteExpectations)).Methods(http.MethodDelete)
+	r.Handle("/expectations/{id}/verify", require(auth.RoleOperator, h.verifyExpectations)).Methods(http.MethodGet)
+}
+
+func (h *Handler) listServices(w http.ResponseWriter, r *http.Request) {
+	cfg := h.services.Config()
+
+	services := make([]Service, 0, len(cfg.Services))
+	for name, svc := range cfg.Services {
+		services = append(services, Service{Name: name, ServiceConfig: svc})
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	writeJSON(w, http.StatusOK, services)
+}
+
+func (h *Handler) getService(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	svc, ok := h.services.Config().Services[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Service{Name: name, ServiceConfig: svc})
+}
+
+func (h *Handler) createService(w http.ResponseWriter, r *http.Request) {
+	var svc Service
+	if err := json.NewDecoder(r.Body).Decode(&svc); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if svc.Name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("name is required"))
+		return
+	}
+
+	err := h.services.Update(func(cfg *config.Config) error {
+		if _, ok := cfg.Services[svc.Name]; ok {
+			return errExists
+		}
+		cfg.Services[svc.Name] = svc.ServiceConfig
+		return nil
+	})
+	if err != nil {
+		writeUpdateError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, svc)
+}
+
+func (h *Handler) updateService(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var svc config.ServiceConfig
+	if err := json.NewDecoder(r.Body).Decode(&svc); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err := h.services.Update(func(cfg *config.Config) error {
+		if _, ok := cfg.Services[name]; !ok {
+			return errNotFound
+		}
+		cfg.Services[name] = svc
+		return nil
+	})
+	if err != nil {
+		writeUpdateError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Service{Name: name, ServiceConfig: svc})
+}
+
+func (h *Handler) deleteService(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	err := h.services.Update(func(cfg *config.Config) error {
+		if _, ok := cfg.Services[name]; !ok {
+			return errNotFound
+		}
+		delete(cfg.Services, name)
+		return nil
+	})
+	if err != nil {
+		writeUpdateError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) setDisabled(disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+
+		var svc config.ServiceConfig
+		err := h.services.Update(func(cfg *config.Config) error {
+			var ok bool
+			svc, ok = cfg.Services[name]
+			if !ok {
+				return errNotFound
+			}
+			svc.Disabled = disabled
+			cfg.Services[name] = svc
+			return nil
+		})
+		if err != nil {
+			writeUpdateError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, Service{Name: name, ServiceConfig: svc})
+	}
+}
+
+func (h *Handler) exportCaptures(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="golden-gate-captures.json"`)
+	capture.Write(w, h.requestStore.GetRequests())
+}
+
+func (h *Handler) importCaptures(w http.ResponseWriter, r *http.Request) {
+	file, err := capture.Read(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	for _, req := range file.Requests {
+		h.requestStore.AddRequest(req)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"imported": len(file.Requests)})
+}
+
+func writeUpdateError(w http.ResponseWriter, err error) {
+	var validationErr *config.ValidationError
+	switch {
+	case errors.Is(err, errNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, errExists), errors.Is(err, config.ErrEnvReference):
+		writeError(w, http.StatusConflict, err)
+	case errors.As(err, &validationErr):
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("saving config: %w", err))
+	}
+}
+
+// writeBodyError reports a request body that could not be read or decoded.
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body larger than %d bytes", tooLarge.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, err)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
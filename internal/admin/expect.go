@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mtavano/golden-gate/internal/expect"
+)
+
+// maxVerifyWait bounds how long a verification waits for the expectations to
+// be met.
+const maxVerifyWait = time.Minute
+
+// maxExpectationsSize bounds the body of a new expectation set.
+const maxExpectationsSize = 1 << 20
+
+var errSetNotFound = errors.New("expectation set not found")
+
+type expectationSet struct {
+	Name         string               `json:"name"`
+	Expectations []expect.Expectation `json:"expectations"`
+}
+
+func (h *Handler) listExpectations(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.expectations.List())
+}
+
+// createExpectations registers a set. Only requests captured from now on are
+// checked against it.
+func (h *Handler) createExpectations(w http.ResponseWriter, r *http.Request) {
+	var body expectationSet
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxExpectationsSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	set, err := h.expectations.Add(body.Name, body.Expectations, h.requestStore.LastSeq())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, set)
+}
+
+func (h *Handler) getExpectations(w http.ResponseWriter, r *http.Request) {
+	set := h.expectations.Get(mux.Vars(r)["id"])
+	if set == nil {
+		writeError(w, http.StatusNotFound, errSetNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, set)
+}
+
+func (h *Handler) deleteExpectations(w http.ResponseWriter, r *http.Request) {
+	if !h.expectations.Delete(mux.Vars(r)["id"]) {
+		writeError(w, http.StatusNotFound, errSetNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verifyExpectations reports whether the set is met. With ?wait=5s it
+// re-checks as requests are captured, until the set passes or the wait is
+// over, for scenarios whose requests happen asynchronously.
+func (h *Handler) verifyExpectations(w http.ResponseWriter, r *http.Request) {
+	set := h.expectations.Get(mux.Vars(r)["id"])
+	if set == nil {
+		writeError(w, http.StatusNotFound, errSetNotFound)
+		return
+	}
+	var wait time.Duration
+	if v := r.URL.Query().Get("wait"); v != "" {
+		var err error
+		if wait, err = time.ParseDuration(v); err != nil || wait < 0 || wait > maxVerifyWait {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("wait: must be a duration up to %s", maxVerifyWait))
+			return
+		}
+	}
+
+	// Subscribe before the first check so no capture falls in between
+	events, unsubscribe := h.requestStore.Subscribe(256)
+	defer unsubscribe()
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+
+	for {
+		report := set.Verify(h.requestStore.GetRequests())
+		if report.Passed || wait == 0 {
+			writeJSON(w, http.StatusOK, report)
+			return
+		}
+		select {
+		case <-events:
+		case <-deadline.C:
+			writeJSON(w, http.StatusOK, set.Verify(h.requestStore.GetRequests()))
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
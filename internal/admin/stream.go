@@ -0,0 +1,162 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/filter"
+	"github.com/mtavano/golden-gate/internal/types"
+	"go.uber.org/zap"
+)
+
+// streamHeartbeat is how often an empty line is sent on an idle stream, so
+// proxies keep the connection open and clients notice when it drops.
+const streamHeartbeat = 15 * time.Second
+
+// streamCaptures writes completed exchanges as newline-delimited JSON as they
+// happen. It takes the dashboard filter parameters, plus:
+//
+//	last   first send up to this many of the stored exchanges
+//	after  first send the stored exchanges with a greater seq, to resume
+func (h *Handler) streamCaptures(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	f, err := filter.Parse(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var (
+		last  int
+		after uint64
+	)
+	if v := query.Get("last"); v != "" {
+		if last, err = strconv.Atoi(v); err != nil || last < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("last: must be a positive number"))
+			return
+		}
+	}
+	if v := query.Get("after"); v != "" {
+		if after, err = strconv.ParseUint(v, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("after: must be a sequence number"))
+			return
+		}
+	}
+
+	// Subscribe before taking the snapshot so nothing falls in between
+	events, unsubscribe := h.requestStore.Subscribe(256)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	controller := http.NewResponseController(w)
+
+	// The stream covers exchanges captured from now on and those still
+	// waiting for the upstream, which is what a catch-up looks for when
+	// events were dropped
+	since := h.requestStore.LastSeq()
+	snapshot := h.requestStore.GetRequests()
+	waiting := map[uint64]bool{}
+	var backlog []*types.RequestLog
+	for _, req := range snapshot {
+		if req.Pending {
+			waiting[req.Seq] = true
+		} else if (last > 0 || query.Has("after")) && req.Seq > after && f.Match(req) {
+			backlog = append(backlog, req)
+		}
+	}
+	if last > 0 && len(backlog) > last {
+		backlog = backlog[len(backlog)-last:]
+	}
+	// Events queued while taking the snapshot or catching up may repeat
+	// exchanges already sent
+	sent := map[uint64]bool{}
+	send := func(req *types.RequestLog) error {
+		sent[req.Seq] = true
+		delete(waiting, req.Seq)
+		return encoder.Encode(req)
+	}
+	for _, req := range backlog {
+		send(req)
+	}
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	// catchUp sends the exchanges whose events were dropped because the
+	// client read too slowly
+	var dropped uint64
+	catchUp := func() error {
+		n := h.requestStore.Dropped(events)
+		if n == dropped {
+			return nil
+		}
+		zap.L().Warn("capture stream fell behind, catching up from the store",
+			zap.Uint64("dropped_events", n-dropped))
+		dropped = n
+		for _, req := range h.requestStore.GetRequests() {
+			if req.Pending || sent[req.Seq] || (req.Seq <= since && !waiting[req.Seq]) || !f.Match(req) {
+				continue
+			}
+			if err := send(req); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// forget drops the bookkeeping of exchanges no longer stored
+	forget := func() {
+		requests := h.requestStore.GetRequests()
+		if len(requests) == 0 {
+			return
+		}
+		oldest := requests[0].Seq
+		for seq := range sent {
+			if seq < oldest {
+				delete(sent, seq)
+			}
+		}
+		for seq := range waiting {
+			if seq < oldest {
+				delete(waiting, seq)
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			forget()
+			if err := catchUp(); err != nil {
+				return
+			}
+			w.Write([]byte("\n"))
+		case event := <-events:
+			if event.Request.Pending || sent[event.Request.Seq] || !f.Match(event.Request) {
+				continue
+			}
+			if err := send(event.Request); err != nil {
+				return
+			}
+			if len(events) == 0 {
+				if err := catchUp(); err != nil {
+					return
+				}
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
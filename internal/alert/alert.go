@@ -0,0 +1,364 @@
+// Package alert evaluates the configured alert rules over the captured
+// traffic and notifies webhooks when they fire and resolve.
+package alert
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/config"
+	"github.com/mtavano/golden-gate/internal/filter"
+	"github.com/mtavano/golden-gate/internal/stats"
+	"github.com/mtavano/golden-gate/internal/types"
+	"go.uber.org/zap"
+)
+
+// maxHistory is the number of notifications kept for the dashboard.
+const maxHistory = 50
+
+// Rule is the current state of one alert rule.
+type Rule struct {
+	Name      string
+	Match     string
+	Condition string
+	Window    time.Duration
+	Firing    bool
+	// Since is when the rule last started firing or resolved.
+	Since time.Time
+	// Value is the metric at the last evaluation, empty while the window
+	// holds fewer than MinRequests requests.
+	Value     string
+	Requests  int
+	Evaluated time.Time
+}
+
+// Manager keeps a sliding window of completed requests per rule. Rules whose
+// definition is unchanged keep their window and state across reloads.
+type Manager struct {
+	store    *types.RequestStore
+	notifier *notifier
+	// reset tells Run that the evaluation interval may have changed.
+	reset chan struct{}
+
+	mu       sync.Mutex
+	interval time.Duration
+	rules    []*rule
+	webhooks []config.Webhook
+	history  []Notification
+}
+
+type rule struct {
+	config    config.AlertRule
+	condition config.AlertCondition
+	filter    *filter.Filter
+	samples   []sample
+
+	firing    bool
+	since     time.Time
+	value     string
+	requests  int
+	evaluated time.Time
+}
+
+// sample is a request that completed at the given time.
+type sample struct {
+	at       time.Time
+	duration time.Duration
+	failed   bool
+}
+
+func NewManager(store *types.RequestStore) *Manager {
+	return &Manager{
+		store:    store,
+		notifier: newNotifier(),
+		reset:    make(chan struct{}, 1),
+		interval: 10 * time.Second,
+	}
+}
+
+// Configure replaces the rules and webhooks, which must have been validated.
+func (m *Manager) Configure(cfg config.AlertsConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous := map[string]*rule{}
+	for _, r := range m.rules {
+		previous[r.config.Name] = r
+	}
+
+	rules := make([]*rule, 0, len(cfg.Rules))
+	for _, ruleConfig := range cfg.Rules {
+		if r, ok := previous[ruleConfig.Name]; ok && sameDefinition(r.config, ruleConfig) {
+			// Only the webhooks may differ
+			r.config = ruleConfig
+			rules = append(rules, r)
+			continue
+		}
+		condition, err := config.ParseAlertCondition(ruleConfig.Condition)
+		if err != nil {
+			zap.L().Error("skipping alert rule", zap.String("rule", ruleConfig.Name), zap.Error(err))
+			continue
+		}
+		f, err := filter.ParseTerms(ruleConfig.Match)
+		if err != nil {
+			zap.L().Error("skipping alert rule", zap.String("rule", ruleConfig.Name), zap.Error(err))
+			continue
+		}
+		rules = append(rules, &rule{config: ruleConfig, condition: condition, filter: f})
+	}
+
+	m.rules = rules
+	m.webhooks = cfg.Webhooks
+	if interval := time.Duration(cfg.Interval); interval > 0 && interval != m.interval {
+		m.interval = interval
+		select {
+		case m.reset <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func sameDefinition(a, b config.AlertRule) bool {
+	return a.Match == b.Match && a.Condition == b.Condition && a.Window == b.Window && a.MinRequests == b.MinRequests
+}
+
+// Run records completed requests and evaluates the rules until ctx is done.
+func (m *Manager) Run(ctx context.Context) {
+	events, unsubscribe := m.store.Subscribe(1024)
+	defer unsubscribe()
+
+	// Requests captured from now on, and those still waiting for the
+	// upstream, are recorded; a catch-up looks for them in the store when
+	// events were dropped
+	since := m.store.LastSeq()
+	waiting := map[uint64]bool{}
+	for _, req := range m.store.GetRequests() {
+		if req.Pending {
+			waiting[req.Seq] = true
+		}
+	}
+	recorded := map[uint64]bool{}
+	record := func(req *types.RequestLog) {
+		if req.Pending || recorded[req.Seq] {
+			return
+		}
+		recorded[req.Seq] = true
+		delete(waiting, req.Seq)
+		m.record(req)
+	}
+
+	var dropped uint64
+	catchUp := func() {
+		requests := m.store.GetRequests()
+		if len(requests) > 0 {
+			// Forget the requests no longer stored
+			oldest := requests[0].Seq
+			for seq := range recorded {
+				if seq < oldest {
+					delete(recorded, seq)
+				}
+			}
+			for seq := range waiting {
+				if seq < oldest {
+					delete(waiting, seq)
+				}
+			}
+		}
+
+		n := m.store.Dropped(events)
+		if n == dropped {
+			return
+		}
+		zap.L().Warn("alert rules fell behind, catching up from the store",
+			zap.Uint64("dropped_events", n-dropped))
+		dropped = n
+		for _, req := range requests {
+			if req.Seq > since || waiting[req.Seq] {
+				record(req)
+			}
+		}
+	}
+
+	ticker := time.NewTicker(m.currentInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			record(event.Request)
+		case now := <-ticker.C:
+			catchUp()
+			for _, n := range m.evaluate(now) {
+				m.notifier.send(ctx, n.webhooks, n.Notification)
+			}
+		case <-m.reset:
+			ticker.Reset(m.currentInterval())
+		}
+	}
+}
+
+func (m *Manager) currentInterval() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.interval
+}
+
+func (m *Manager) record(req *types.RequestLog) {
+	s := sample{
+		at:       req.Timestamp.Add(req.Duration),
+		duration: req.Duration,
+		failed:   stats.IsError(req),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.rules {
+		if r.filter.Match(req) {
+			r.samples = append(r.samples, s)
+		}
+	}
+}
+
+// pending is a notification with the webhooks it goes to.
+type pending struct {
+	Notification
+	webhooks []config.Webhook
+}
+
+// evaluate updates every rule at now and returns the notifications for the
+// rules that started firing or resolved.
+func (m *Manager) evaluate(now time.Time) []pending {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var notifications []pending
+	for _, r := range m.rules {
+		holds := r.evaluate(now)
+		if holds == r.firing {
+			continue
+		}
+
+		n := Notification{
+			Status:    StatusFiring,
+			Rule:      r.config.Name,
+			Match:     r.config.Match,
+			Condition: r.config.Condition,
+			Window:    time.Duration(r.config.Window).String(),
+			Value:     r.value,
+			Requests:  r.requests,
+			StartedAt: now,
+		}
+		if holds {
+			r.firing, r.since = true, now
+		} else {
+			n.Status = StatusResolved
+			n.StartedAt = r.since
+			n.ResolvedAt = &now
+			r.firing, r.since = false, now
+		}
+		n.Summary = n.summary()
+
+		zap.L().Info("alert "+n.Status, zap.String("rule", n.Rule), zap.String("value", n.Value))
+		m.history = append(m.history, n)
+		if len(m.history) > maxHistory {
+			m.history = m.history[len(m.history)-maxHistory:]
+		}
+		notifications = append(notifications, pending{Notification: n, webhooks: m.targets(r.config)})
+	}
+	return notifications
+}
+
+// evaluate drops the samples that left the window and reports whether the
+// condition holds for the rest.
+func (r *rule) evaluate(now time.Time) bool {
+	start := now.Add(-time.Duration(r.config.Window))
+	r.samples = slices.DeleteFunc(r.samples, func(s sample) bool { return s.at.Before(start) })
+	r.requests = len(r.samples)
+	r.evaluated = now
+	r.value = ""
+
+	var value float64
+	switch r.condition.Metric {
+	case "count":
+		value = float64(len(r.samples))
+	case "error_rate":
+		if len(r.samples) < r.config.MinRequests || len(r.samples) == 0 {
+			return false
+		}
+		failed := 0
+		for _, s := range r.samples {
+			if s.failed {
+				failed++
+			}
+		}
+		value = float64(failed) / float64(len(r.samples))
+	default:
+		if len(r.samples) < r.config.MinRequests || len(r.samples) == 0 {
+			return false
+		}
+		durations := make([]time.Duration, len(r.samples))
+		for i, s := range r.samples {
+			durations[i] = s.duration
+		}
+		slices.Sort(durations)
+		p := map[string]int{"p50": 50, "p90": 90, "p95": 95, "p99": 99}[r.condition.Metric]
+		value = stats.Percentile(durations, p).Seconds()
+	}
+	r.value = r.condition.Format(value)
+	return r.condition.Holds(value)
+}
+
+// targets returns the webhooks notified for rule.
+func (m *Manager) targets(rule config.AlertRule) []config.Webhook {
+	if len(rule.Webhooks) == 0 {
+		return m.webhooks
+	}
+	var webhooks []config.Webhook
+	for _, webhook := range m.webhooks {
+		if slices.Contains(rule.Webhooks, webhook.Name) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks
+}
+
+// Rules returns the state of every rule, in config order.
+func (m *Manager) Rules() []Rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rules := make([]Rule, len(m.rules))
+	for i, r := range m.rules {
+		rules[i] = Rule{
+			Name:      r.config.Name,
+			Match:     r.config.Match,
+			Condition: r.config.Condition,
+			Window:    time.Duration(r.config.Window),
+			Firing:    r.firing,
+			Since:     r.since,
+			Value:     r.value,
+			Requests:  r.requests,
+			Evaluated: r.evaluated,
+		}
+	}
+	return rules
+}
+
+// Firing returns the rules that are firing.
+func (m *Manager) Firing() []Rule {
+	return slices.DeleteFunc(m.Rules(), func(r Rule) bool { return !r.Firing })
+}
+
+// History returns the most recent notifications, newest first.
+func (m *Manager) History() []Notification {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	history := slices.Clone(m.history)
+	slices.Reverse(history)
+	return history
+}
@@ -0,0 +1,127 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/config"
+	"go.uber.org/zap"
+)
+
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+const (
+	webhookTimeout  = 10 * time.Second
+	webhookAttempts = 3
+)
+
+// Notification is the JSON body posted to webhooks.
+type Notification struct {
+	// Status is firing or resolved.
+	Status    string `json:"status"`
+	Rule      string `json:"rule"`
+	Match     string `json:"match,omitempty"`
+	Condition string `json:"condition"`
+	Window    string `json:"window"`
+	// Value is the metric when the rule changed state, empty if the
+	// window held too few requests.
+	Value      string     `json:"value,omitempty"`
+	Requests   int        `json:"requests"`
+	StartedAt  time.Time  `json:"started_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	Summary    string     `json:"summary"`
+}
+
+// summary describes n in one line, like
+// "[FIRING] buda-errors: count > 5 over 1m0s, now 7 (service=buda status=5xx)".
+func (n Notification) summary() string {
+	value := n.Value
+	if value == "" {
+		value = "no data"
+	}
+	s := fmt.Sprintf("[%s] %s: %s over %s, now %s", strings.ToUpper(n.Status), n.Rule, n.Condition, n.Window, value)
+	if n.Match != "" {
+		s += " (" + n.Match + ")"
+	}
+	return s
+}
+
+type notifier struct {
+	client *http.Client
+}
+
+func newNotifier() *notifier {
+	return &notifier{client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// send posts n to every webhook in the background, retrying failed
+// attempts. Failures are only logged.
+func (nt *notifier) send(ctx context.Context, webhooks []config.Webhook, n Notification) {
+	for _, webhook := range webhooks {
+		go func() {
+			if err := nt.post(ctx, webhook, n); err != nil {
+				zap.L().Warn("alert webhook failed",
+					zap.String("webhook", webhook.Name),
+					zap.String("rule", n.Rule),
+					zap.Error(err),
+				)
+			}
+		}()
+	}
+}
+
+func (nt *notifier) post(ctx context.Context, webhook config.Webhook, n Notification) error {
+	var payload any = n
+	if webhook.Format == "slack" {
+		payload = map[string]string{"text": n.Summary}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := range webhookAttempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return lastErr
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+		if lastErr = nt.attempt(ctx, webhook, body); lastErr == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+func (nt *notifier) attempt(ctx context.Context, webhook config.Webhook, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golden-gate")
+	for name, value := range webhook.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := nt.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s", webhook.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Role grants access to the dashboard and the admin API. Each role includes
+// the ones below it.
+type Role int
+
+const (
+	RoleNone Role = iota
+	// RoleViewer reads captures, with credentials redacted.
+	RoleViewer
+	// RoleOperator sees captures unredacted and acts on traffic, like
+	// importing and exporting captures.
+	RoleOperator
+	// RoleAdmin also changes the configuration.
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RoleViewer:   "viewer",
+	RoleOperator: "operator",
+	RoleAdmin:    "admin",
+}
+
+// ParseRole returns the role with the given config name, or RoleNone.
+func ParseRole(name string) Role {
+	for role, roleName := range roleNames {
+		if roleName == name {
+			return role
+		}
+	}
+	return RoleNone
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// Allows reports whether r includes required.
+func (r Role) Allows(required Role) bool {
+	return r >= required
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Name string
+	Role Role
+	// Method is how the caller signed in: password, token or oidc.
+	Method string
+	// Anonymous is set for every caller when authentication is disabled.
+	Anonymous bool
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying p.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal stored by Require, or nil.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(contextKey{}).(*Principal)
+	return p
+}
+
+// ErrInvalidCredentials is returned for an unknown user or a wrong password.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// dummyHash is compared against for unknown users, so a login takes as long
+// whether or not the user exists.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("golden-gate"), bcrypt.DefaultCost)
+
+type user struct {
+	hash []byte
+	role Role
+}
+
+type token struct {
+	name string
+	sum  [sha256.Size]byte
+	role Role
+}
+
+// Authenticator checks the credentials of dashboard and admin API requests.
+// It is rebuilt with the router on every config reload.
+type Authenticator struct {
+	open      bool
+	loginPath string
+	users     map[string]user
+	tokens    []token
+	sessions  *codec
+	ttl       time.Duration
+	oidc      *provider
+}
+
+// New creates the authenticator for cfg. secret signs session cookies when
+// the config has no session secret. adminToken, from GOLDEN_GATE_ADMIN_TOKEN,
+// is accepted as an admin bearer token.
+func New(cfg *config.Config, secret []byte, adminToken string) *Authenticator {
+	if cfg.Auth.SessionSecret != "" {
+		secret = []byte(cfg.Auth.SessionSecret)
+	}
+
+	a := &Authenticator{
+		open:     !cfg.AuthEnabled(),
+		users:    map[string]user{},
+		sessions: &codec{secret: secret},
+		ttl:      time.Duration(cfg.Auth.SessionTTL),
+	}
+	if cfg.DashboardEnabled() {
+		a.loginPath = cfg.Dashboard.Path + "/login"
+	}
+	for _, u := range cfg.Auth.Users {
+		a.users[u.Username] = user{hash: []byte(u.PasswordHash), role: ParseRole(u.Role)}
+	}
+	for _, t := range cfg.Auth.Tokens {
+		a.tokens = append(a.tokens, token{name: t.Name, sum: sha256.Sum256([]byte(t.Token)), role: ParseRole(t.Role)})
+	}
+	if adminToken != "" {
+		a.tokens = append(a.tokens, token{name: "admin-token", sum: sha256.Sum256([]byte(adminToken)), role: RoleAdmin})
+	}
+	if cfg.Auth.OIDC != nil {
+		a.oidc = newProvider(*cfg.Auth.OIDC, cfg.Dashboard.Path)
+	}
+	return a
+}
+
+// Open reports whether authentication is disabled, letting every caller in
+// anonymously.
+func (a *Authenticator) Open() bool {
+	return a.open
+}
+
+// HasTokens reports whether any bearer token is configured.
+func (a *Authenticator) HasTokens() bool {
+	return len(a.tokens) > 0
+}
+
+// PasswordsEnabled reports whether users can sign in with a password.
+func (a *Authenticator) PasswordsEnabled() bool {
+	return len(a.users) > 0
+}
+
+// OIDCEnabled reports whether users can sign in with OpenID Connect.
+func (a *Authenticator) OIDCEnabled() bool {
+	return a.oidc != nil
+}
+
+// Authenticate returns the caller of r, from a bearer token, HTTP basic
+// credentials or a session cookie, or nil. Session cookies are only accepted
+// for GET and HEAD requests, so other sites cannot make changes on a signed
+// in user's behalf.
+func (a *Authenticator) Authenticate(r *http.Request) *Principal {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if bearer, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return a.checkToken(bearer)
+		}
+		if username, password, ok := r.BasicAuth(); ok {
+			p, _ := a.Login(username, password)
+			return p
+		}
+		return nil
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return nil
+	}
+	return a.session(r)
+}
+
+func (a *Authenticator) checkToken(bearer string) *Principal {
+	sum := sha256.Sum256([]byte(bearer))
+	var found *token
+	for i := range a.tokens {
+		if subtle.ConstantTimeCompare(sum[:], a.tokens[i].sum[:]) == 1 {
+			found = &a.tokens[i]
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	return &Principal{Name: found.name, Role: found.role, Method: "token"}
+}
+
+// Login checks a username and password.
+func (a *Authenticator) Login(username, password string) (*Principal, error) {
+	u, ok := a.users[username]
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword(u.hash, []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Name: username, Role: u.role, Method: "password"}, nil
+}
+
+// Require serves next only to callers with at least the given role. Browsers
+// without credentials are sent to the login page; other callers get 401.
+// When authentication is disabled every caller is an anonymous admin.
+func (a *Authenticator) Require(role Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := a.Authenticate(r)
+		if p == nil && a.open {
+			p = &Principal{Role: RoleAdmin, Anonymous: true}
+		}
+		if p == nil {
+			a.unauthorized(w, r)
+			return
+		}
+		if !p.Role.Allows(role) {
+			writeError(w, http.StatusForbidden, "the "+p.Role.String()+" role cannot do this, "+role.String()+" is required")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), p)))
+	})
+}
+
+func (a *Authenticator) unauthorized(w http.ResponseWriter, r *http.Request) {
+	if a.loginPath != "" && r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+		http.Redirect(w, r, a.loginPath+"?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+		return
+	}
+
+	w.Header().Set("WWW-Authenticate", `Bearer realm="golden-gate"`)
+	writeError(w, http.StatusUnauthorized, "invalid or missing credentials")
+}
+
+// SafeNext returns next if it is a path on this host, or fallback.
+func SafeNext(next, fallback string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return fallback
+	}
+	return next
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
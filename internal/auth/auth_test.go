@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mtavano/golden-gate/internal/config"
+)
+
+func TestSafeNext(t *testing.T) {
+	tests := []struct {
+		next string
+		want string
+	}{
+		{"/dashboard/requests?service=buda", "/dashboard/requests?service=buda"},
+		{"/", "/"},
+		{"", "/fallback"},
+		{"dashboard", "/fallback"},
+		{"//evil.example.com", "/fallback"},
+		{"//evil.example.com/dashboard", "/fallback"},
+		{`/\evil.example.com`, "/fallback"},
+		{"https://evil.example.com", "/fallback"},
+		{"javascript:alert(1)", "/fallback"},
+	}
+	for _, tt := range tests {
+		if got := SafeNext(tt.next, "/fallback"); got != tt.want {
+			t.Errorf("SafeNext(%q) = %q, want %q", tt.next, got, tt.want)
+		}
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role     Role
+		required Role
+		want     bool
+	}{
+		{RoleAdmin, RoleAdmin, true},
+		{RoleAdmin, RoleOperator, true},
+		{RoleAdmin, RoleViewer, true},
+		{RoleOperator, RoleAdmin, false},
+		{RoleOperator, RoleOperator, true},
+		{RoleOperator, RoleViewer, true},
+		{RoleViewer, RoleOperator, false},
+		{RoleViewer, RoleViewer, true},
+		{RoleNone, RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Allows(tt.required); got != tt.want {
+			t.Errorf("%v.Allows(%v) = %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	for _, role := range []Role{RoleViewer, RoleOperator, RoleAdmin} {
+		if got := ParseRole(role.String()); got != role {
+			t.Errorf("ParseRole(%q) = %v, want %v", role.String(), got, role)
+		}
+	}
+	for _, name := range []string{"", "Admin", "root"} {
+		if got := ParseRole(name); got != RoleNone {
+			t.Errorf("ParseRole(%q) = %v, want none", name, got)
+		}
+	}
+}
+
+func TestRequire(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Auth.Tokens = []config.AuthToken{
+		{Name: "ci", Token: "viewer-token", Role: "viewer"},
+		{Name: "ops", Token: "operator-token", Role: "operator"},
+	}
+	a := New(cfg, []byte("secret"), "admin-token")
+	handler := a.Require(RoleOperator, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(FromContext(r.Context()).Name))
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantBody      string
+	}{
+		{"operator token", "Bearer operator-token", http.StatusOK, "ops"},
+		{"admin token", "Bearer admin-token", http.StatusOK, "admin-token"},
+		{"viewer token", "Bearer viewer-token", http.StatusForbidden, ""},
+		{"unknown token", "Bearer nope", http.StatusUnauthorized, ""},
+		{"no credentials", "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/admin/captures", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
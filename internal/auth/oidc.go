@@ -0,0 +1,434 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/config"
+)
+
+const (
+	oidcCookie   = "golden_gate_oidc"
+	oidcLoginTTL = 10 * time.Minute
+	// keysRefresh limits how often an unknown key id refetches the JWKS.
+	keysRefresh = time.Minute
+	clockSkew   = time.Minute
+)
+
+var signingHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+// provider talks to an OpenID Connect issuer. Its discovery document and
+// signing keys are fetched on first use, so an unreachable issuer does not
+// stop the server from starting.
+type provider struct {
+	cfg          config.OIDCConfig
+	callbackPath string
+	client       *http.Client
+
+	mu        sync.Mutex
+	metadata  *metadata
+	keys      map[string]*rsa.PublicKey
+	keysFetch time.Time
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// loginState is kept in a signed cookie between the redirect to the issuer
+// and the callback.
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Redirect string `json:"redirect"`
+	Next     string `json:"next"`
+	Expires  int64  `json:"exp"`
+}
+
+func newProvider(cfg config.OIDCConfig, dashboardPath string) *provider {
+	return &provider{
+		cfg:          cfg,
+		callbackPath: dashboardPath + "/oidc/callback",
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// OIDCLogin redirects the browser to the issuer. After signing in the user
+// returns to the callback and then to next.
+func (a *Authenticator) OIDCLogin(w http.ResponseWriter, r *http.Request, next string) error {
+	if a.oidc == nil {
+		return errors.New("OIDC is not configured")
+	}
+	md, err := a.oidc.discover(r.Context())
+	if err != nil {
+		return err
+	}
+
+	state := loginState{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: randomString(),
+		Redirect: a.oidc.redirectURL(r),
+		Next:     next,
+		Expires:  time.Now().Add(oidcLoginTTL).Unix(),
+	}
+	value, err := a.sessions.encode(state)
+	if err != nil {
+		return err
+	}
+	setCookie(w, r, oidcCookie, value, time.Unix(state.Expires, 0))
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {a.oidc.cfg.ClientID},
+		"redirect_uri":          {state.Redirect},
+		"scope":                 {strings.Join(a.oidc.cfg.Scopes, " ")},
+		"state":                 {state.State},
+		"nonce":                 {state.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(md.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	http.Redirect(w, r, md.AuthorizationEndpoint+separator+query.Encode(), http.StatusFound)
+	return nil
+}
+
+// OIDCCallback completes a login started by OIDCLogin. It returns the signed
+// in principal and the path to continue to.
+func (a *Authenticator) OIDCCallback(w http.ResponseWriter, r *http.Request) (*Principal, string, error) {
+	if a.oidc == nil {
+		return nil, "", errors.New("OIDC is not configured")
+	}
+
+	cookie, err := r.Cookie(oidcCookie)
+	if err != nil {
+		return nil, "", errors.New("the login expired, please try again")
+	}
+	setCookie(w, r, oidcCookie, "", time.Unix(0, 0))
+
+	var state loginState
+	if err := a.sessions.decode(cookie.Value, &state); err != nil || time.Now().Unix() > state.Expires {
+		return nil, "", errors.New("the login expired, please try again")
+	}
+
+	query := r.URL.Query()
+	if code := query.Get("error"); code != "" {
+		return nil, "", fmt.Errorf("the identity provider refused the login: %s %s", code, query.Get("error_description"))
+	}
+	if query.Get("state") != state.State {
+		return nil, "", errors.New("the login state does not match, please try again")
+	}
+
+	rawToken, err := a.oidc.exchange(r.Context(), query.Get("code"), state)
+	if err != nil {
+		return nil, "", err
+	}
+	claims, err := a.oidc.verify(r.Context(), rawToken, state.Nonce)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	p := &Principal{Name: claims.name(), Role: a.oidc.role(claims), Method: "oidc"}
+	if p.Role == RoleNone {
+		return nil, "", fmt.Errorf("%s has no role in golden-gate", p.Name)
+	}
+	return p, state.Next, nil
+}
+
+func (p *provider) redirectURL(r *http.Request) string {
+	if p.cfg.RedirectURL != "" {
+		return p.cfg.RedirectURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + p.callbackPath
+}
+
+func (p *provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var md metadata
+	issuer := strings.TrimSuffix(p.cfg.Issuer, "/")
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", &md); err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", issuer, err)
+	}
+	if strings.TrimSuffix(md.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery document is for issuer %q, expected %q", md.Issuer, p.cfg.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of %s lacks endpoints", issuer)
+	}
+	p.metadata = &md
+	return p.metadata, nil
+}
+
+func (p *provider) exchange(ctx context.Context, code string, state loginState) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {state.Redirect},
+		"code_verifier": {state.Verifier},
+		"client_id":     {p.cfg.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("exchanging the code: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("exchanging the code: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("exchanging the code: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return "", fmt.Errorf("exchanging the code: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return "", errors.New("the token response has no id_token, is the openid scope requested?")
+	}
+	return tokens.IDToken, nil
+}
+
+type claims map[string]any
+
+func (c claims) string(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+func (c claims) name() string {
+	for _, name := range []string{"preferred_username", "email", "sub"} {
+		if s := c.string(name); s != "" {
+			return s
+		}
+	}
+	return "unknown"
+}
+
+// verify checks the signature and standard claims of an ID token.
+func (p *provider) verify(ctx context.Context, raw, nonce string) (claims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	hash, ok := signingHashes[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", header.Alg)
+	}
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	digest := hash.New()
+	digest.Write([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, hash, digest.Sum(nil), signature); err != nil {
+		return nil, errors.New("bad signature")
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(c.string("iss"), "/") != strings.TrimSuffix(p.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("issued by %q", c.string("iss"))
+	}
+	if !c.hasAudience(p.cfg.ClientID) {
+		return nil, errors.New("issued for another client")
+	}
+	exp, _ := c["exp"].(float64)
+	if time.Now().Add(-clockSkew).After(time.Unix(int64(exp), 0)) {
+		return nil, errors.New("expired")
+	}
+	if c.string("nonce") != nonce {
+		return nil, errors.New("nonce mismatch")
+	}
+	return c, nil
+}
+
+func (c claims) hasAudience(clientID string) bool {
+	switch aud := c["aud"].(type) {
+	case string:
+		return aud == clientID
+	case []any:
+		for _, v := range aud {
+			if v == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// role maps the values of the role claim to the highest matching role.
+func (p *provider) role(c claims) Role {
+	var values []string
+	switch v := c[p.cfg.RoleClaim].(type) {
+	case string:
+		values = []string{v}
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	role := ParseRole(p.cfg.DefaultRole)
+	for _, value := range values {
+		if mapped := ParseRole(p.cfg.Roles[value]); mapped > role {
+			role = mapped
+		}
+	}
+	return role
+}
+
+// key returns the signing key with the given id, refetching the key set when
+// the issuer has rotated its keys.
+func (p *provider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetch) < keysRefresh && p.keys != nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, md.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetching signing keys: %w", err)
+	}
+	p.keysFetch = time.Now()
+	p.keys = map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		p.keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (p *provider) getJSON(ctx context.Context, target string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", target, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.New("malformed token")
+	}
+	return nil
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/config"
+)
+
+// testProvider returns a provider that trusts key under the id "k1", without
+// talking to an issuer.
+func testProvider(key *rsa.PrivateKey) *provider {
+	p := newProvider(config.OIDCConfig{Issuer: "https://issuer.example.com/", ClientID: "golden-gate"}, "/dashboard")
+	p.metadata = &metadata{Issuer: "https://issuer.example.com/"}
+	p.keys = map[string]*rsa.PublicKey{"k1": &key.PublicKey}
+	p.keysFetch = time.Now()
+	return p
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, header, claims map[string]any) string {
+	t.Helper()
+	segment := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(header) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := testProvider(key)
+
+	header := map[string]any{"alg": "RS256", "kid": "k1"}
+	claims := func(changes map[string]any) map[string]any {
+		c := map[string]any{
+			"iss":   "https://issuer.example.com",
+			"aud":   "golden-gate",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "n0nce",
+			"email": "ana@example.com",
+		}
+		for k, v := range changes {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+	valid := signToken(t, key, header, claims(nil))
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"valid", valid, ""},
+		{"audience list", signToken(t, key, header, claims(map[string]any{"aud": []string{"other", "golden-gate"}})), ""},
+		{"within clock skew", signToken(t, key, header, claims(map[string]any{"exp": time.Now().Add(-30 * time.Second).Unix()})), ""},
+		{"malformed", "a.b", "malformed token"},
+		{"alg none", signToken(t, key, map[string]any{"alg": "none", "kid": "k1"}, claims(nil)), "unsupported signing algorithm"},
+		{"alg HS256", signToken(t, key, map[string]any{"alg": "HS256", "kid": "k1"}, claims(nil)), "unsupported signing algorithm"},
+		{"unknown kid", signToken(t, key, map[string]any{"alg": "RS256", "kid": "k2"}, claims(nil)), "unknown signing key"},
+		{"other key", signToken(t, other, header, claims(nil)), "bad signature"},
+		{"claims swapped", parts[0] + "." + strings.Split(signToken(t, key, header, claims(map[string]any{"email": "eve@example.com"})), ".")[1] + "." + parts[2], "bad signature"},
+		{"other issuer", signToken(t, key, header, claims(map[string]any{"iss": "https://evil.example.com"})), "issued by"},
+		{"other audience", signToken(t, key, header, claims(map[string]any{"aud": "other"})), "another client"},
+		{"no audience", signToken(t, key, header, claims(map[string]any{"aud": nil})), "another client"},
+		{"expired", signToken(t, key, header, claims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})), "expired"},
+		{"no expiry", signToken(t, key, header, claims(map[string]any{"exp": nil})), "expired"},
+		{"other nonce", signToken(t, key, header, claims(map[string]any{"nonce": "replayed"})), "nonce mismatch"},
+		{"no nonce", signToken(t, key, header, claims(map[string]any{"nonce": nil})), "nonce mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := p.verify(context.Background(), tt.token, "n0nce")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verify = %v", err)
+				}
+				if c.name() != "ana@example.com" {
+					t.Errorf("name = %q, want ana@example.com", c.name())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("verify = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProviderRole(t *testing.T) {
+	p := newProvider(config.OIDCConfig{
+		RoleClaim: "groups",
+		Roles:     map[string]string{"eng": "viewer", "sre": "operator", "leads": "admin"},
+	}, "/dashboard")
+
+	tests := []struct {
+		name        string
+		groups      any
+		defaultRole string
+		want        Role
+	}{
+		{"highest match wins", []any{"eng", "leads", "sre"}, "", RoleAdmin},
+		{"single string", "sre", "", RoleOperator},
+		{"no match", []any{"sales"}, "", RoleNone},
+		{"no claim", nil, "", RoleNone},
+		{"default role", []any{"sales"}, "viewer", RoleViewer},
+		{"match above the default", []any{"sre"}, "viewer", RoleOperator},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.cfg.DefaultRole = tt.defaultRole
+			c := claims{}
+			if tt.groups != nil {
+				c["groups"] = tt.groups
+			}
+			if got := p.role(c); got != tt.want {
+				t.Errorf("role = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const sessionCookie = "golden_gate_session"
+
+var errBadCookie = errors.New("invalid or expired cookie")
+
+// codec signs cookie values with HMAC-SHA256. Values are not encrypted, so
+// they must not hold secrets.
+type codec struct {
+	secret []byte
+}
+
+func (c *codec) encode(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload)), nil
+}
+
+func (c *codec) decode(value string, v any) error {
+	payload, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return errBadCookie
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sum, c.sign(payload)) {
+		return errBadCookie
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return errBadCookie
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *codec) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+type session struct {
+	Name    string `json:"name"`
+	Role    string `json:"role"`
+	Method  string `json:"method"`
+	Expires int64  `json:"exp"`
+}
+
+// StartSession signs p in on this browser until the session TTL passes.
+func (a *Authenticator) StartSession(w http.ResponseWriter, r *http.Request, p *Principal) error {
+	expires := time.Now().Add(a.ttl)
+	value, err := a.sessions.encode(session{
+		Name:    p.Name,
+		Role:    p.Role.String(),
+		Method:  p.Method,
+		Expires: expires.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	setCookie(w, r, sessionCookie, value, expires)
+	return nil
+}
+
+// EndSession signs the browser out.
+func (a *Authenticator) EndSession(w http.ResponseWriter, r *http.Request) {
+	setCookie(w, r, sessionCookie, "", time.Unix(0, 0))
+}
+
+// session returns the principal of a valid session cookie. Password users
+// get their current role, and lose access once removed from the config.
+func (a *Authenticator) session(r *http.Request) *Principal {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	var s session
+	if err := a.sessions.decode(cookie.Value, &s); err != nil || time.Now().Unix() > s.Expires {
+		return nil
+	}
+
+	p := &Principal{Name: s.Name, Role: ParseRole(s.Role), Method: s.Method}
+	if s.Method == "password" {
+		u, ok := a.users[s.Name]
+		if !ok {
+			return nil
+		}
+		p.Role = u.role
+	} else if a.oidc == nil {
+		return nil
+	}
+	if p.Role == RoleNone {
+		return nil
+	}
+	return p
+}
+
+func setCookie(w http.ResponseWriter, r *http.Request, name, value string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
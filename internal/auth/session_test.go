@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/config"
+)
+
+func TestCodec(t *testing.T) {
+	c := &codec{secret: []byte("secret")}
+	valid, err := c.encode(session{Name: "ana", Role: "viewer", Expires: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, _ := strings.Cut(valid, ".")
+	forged, _ := (&codec{secret: []byte("other")}).encode(session{Name: "ana", Role: "admin"})
+	tampered, _ := c.encode(session{Name: "ana", Role: "admin"})
+	tamperedPayload, _, _ := strings.Cut(tampered, ".")
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"valid", valid, false},
+		{"no signature", payload, true},
+		{"empty signature", payload + ".", true},
+		{"signature not base64", payload + ".!!", true},
+		{"other secret", forged, true},
+		{"payload swapped", tamperedPayload + "." + signature, true},
+		{"signature truncated", payload + "." + signature[:len(signature)-2], true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s session
+			err := c.decode(tt.value, &s)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("decode(%q) = nil, want an error", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode(%q) = %v", tt.value, err)
+			}
+			if s.Name != "ana" || s.Role != "viewer" || s.Expires != 42 {
+				t.Errorf("decode(%q) = %+v", tt.value, s)
+			}
+		})
+	}
+}
+
+func TestSession(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Auth.Users = []config.AuthUser{{Username: "ana", PasswordHash: string(dummyHash), Role: "operator"}}
+	a := New(cfg, []byte("secret"), "")
+	a.ttl = time.Hour
+
+	cookie := func(s session) *http.Cookie {
+		value, err := a.sessions.encode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &http.Cookie{Name: sessionCookie, Value: value}
+	}
+	future := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name     string
+		method   string
+		cookie   *http.Cookie
+		wantRole Role
+	}{
+		{"password user gets the configured role", http.MethodGet, cookie(session{Name: "ana", Role: "admin", Method: "password", Expires: future}), RoleOperator},
+		{"removed user", http.MethodGet, cookie(session{Name: "bob", Role: "admin", Method: "password", Expires: future}), RoleNone},
+		{"expired", http.MethodGet, cookie(session{Name: "ana", Role: "operator", Method: "password", Expires: time.Now().Add(-time.Second).Unix()}), RoleNone},
+		{"oidc without oidc configured", http.MethodGet, cookie(session{Name: "ana", Role: "admin", Method: "oidc", Expires: future}), RoleNone},
+		{"not for changes", http.MethodPost, cookie(session{Name: "ana", Role: "operator", Method: "password", Expires: future}), RoleNone},
+		{"bad signature", http.MethodGet, &http.Cookie{Name: sessionCookie, Value: "e30.AAAA"}, RoleNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/dashboard", nil)
+			r.AddCookie(tt.cookie)
+			p := a.Authenticate(r)
+			got := RoleNone
+			if p != nil {
+				got = p.Role
+			}
+			if got != tt.wantRole {
+				t.Errorf("role = %v, want %v", got, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestStartSession(t *testing.T) {
+	a := New(&config.Config{}, []byte("secret"), "")
+	a.ttl = time.Hour
+	a.users["ana"] = user{role: RoleViewer}
+
+	w := httptest.NewRecorder()
+	if err := a.StartSession(w, httptest.NewRequest(http.MethodPost, "/login", nil), &Principal{Name: "ana", Role: RoleViewer, Method: "password"}); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	for _, c := range w.Result().Cookies() {
+		if !c.HttpOnly || c.SameSite != http.SameSiteLaxMode {
+			t.Errorf("cookie %s is not HttpOnly and SameSite=Lax", c.Name)
+		}
+		r.AddCookie(c)
+	}
+	if p := a.Authenticate(r); p == nil || p.Name != "ana" || p.Role != RoleViewer {
+		t.Errorf("Authenticate = %+v, want ana as viewer", p)
+	}
+}
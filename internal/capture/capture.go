@@ -0,0 +1,82 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+// FormatVersion is the version of the capture file format written by Write.
+const FormatVersion = 1
+
+// File is the on-disk representation of a set of captured exchanges.
+type File struct {
+	Version    int                 `json:"version"`
+	ExportedAt time.Time           `json:"exported_at"`
+	Requests   []*types.RequestLog `json:"requests"`
+}
+
+// Write encodes requests as a capture file.
+func Write(w io.Writer, requests []*types.RequestLog) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(File{
+		Version:    FormatVersion,
+		ExportedAt: time.Now().UTC(),
+		Requests:   requests,
+	})
+}
+
+// Read decodes a capture file written by Write.
+func Read(r io.Reader) (*File, error) {
+	var file File
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if file.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported capture file version %d", file.Version)
+	}
+	return &file, nil
+}
+
+// ReadFile reads the capture file at path.
+func ReadFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// WriteFile writes requests as a capture file at path. The file is replaced
+// atomically so an interrupted write never leaves a truncated capture.
+func WriteFile(path string, requests []*types.RequestLog) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	// CreateTemp makes the file private; captures are shared like any other
+	// file
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := Write(f, requests); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mtavano/golden-gate/internal/capture"
+	"github.com/mtavano/golden-gate/internal/server"
+)
+
+// instanceFlags are shared by the commands that talk to a running instance.
+type instanceFlags struct {
+	url   string
+	token string
+}
+
+func (f *instanceFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.url, "url", "http://localhost:8080", "base URL of the running instance")
+	fs.StringVar(&f.token, "token", os.Getenv(server.AdminTokenEnv), "admin API token")
+}
+
+func (f *instanceFlags) do(method, path string, body io.Reader) (*http.Response, error) {
+	return f.doContext(context.Background(), method, path, body)
+}
+
+func (f *instanceFlags) doContext(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(f.url, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		err := fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+		if hint := f.hint(resp.StatusCode, string(msg)); hint != "" {
+			err = fmt.Errorf("%w\n%s", err, hint)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// hint explains the admin API errors that come from how the instance or the
+// CLI was started rather than from the request.
+func (f *instanceFlags) hint(status int, msg string) string {
+	switch {
+	case status == http.StatusNotFound && strings.Contains(msg, "admin API is disabled"):
+		return fmt.Sprintf("the instance at %s has no admin credentials: start it with %s set, or add tokens to its auth section, then pass the token with -token or %s", f.url, server.AdminTokenEnv, server.AdminTokenEnv)
+	case status == http.StatusUnauthorized && f.token == "":
+		return fmt.Sprintf("no token given: pass one with -token or set %s", server.AdminTokenEnv)
+	}
+	return ""
+}
+
+func runExport(args []string) error {
+	fs := newFlagSet("export")
+	var instance instanceFlags
+	instance.register(fs)
+	output := fs.String("o", "", "write the capture file here instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	resp, err := instance.do(http.MethodGet, "/api/admin/captures", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	file, err := capture.Read(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return capture.Write(os.Stdout, file.Requests)
+	}
+	if err := capture.WriteFile(*output, file.Requests); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d requests to %s\n", len(file.Requests), *output)
+	return nil
+}
+
+func runImport(args []string) error {
+	fs := newFlagSet("import")
+	var instance instanceFlags
+	instance.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one capture file")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	resp, err := instance.do(http.MethodPost, "/api/admin/captures", f)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(os.Stdout, resp.Body)
+	return err
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"serve":         {"Start the proxy and dashboard (default)", runServe},
+	"tail":          {"Print the exchanges of a running instance as they happen", runTail},
+	"tui":           {"Start the proxy and watch its traffic in the terminal", runTUI},
+	"validate":      {"Check a config file and report every problem", runValidate},
+	"export":        {"Download the captured traffic of a running instance", runExport},
+	"import":        {"Load a capture file into a running instance", runImport},
+	"replay":        {"Resend the requests of a capture file against a target", runReplay},
+	"verify":        {"Compare live upstream responses with recorded golden files", runVerify},
+	"openapi":       {"Infer an OpenAPI document for a service from captured traffic", runOpenAPI},
+	"hash-password": {"Print the bcrypt hash of a password for an auth user", runHashPassword},
+	"version":       {"Print version information", runVersion},
+}
+
+// Run executes the subcommand named by args[0] and returns the process exit
+// code. Without arguments it serves, so existing deployments keep working.
+func Run(args []string) int {
+	name := "serve"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		usage(os.Stdout)
+		return 0
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage(os.Stderr)
+		return 2
+	}
+
+	if err := cmd.run(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "golden-gate %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: golden-gate <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-14s %s\n", name, commands[name].summary)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "golden-gate <command> -h" for the flags of a command.`)
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("golden-gate "+name, flag.ContinueOnError)
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/mtavano/golden-gate/internal/capture"
+	"github.com/mtavano/golden-gate/internal/config"
+	"github.com/mtavano/golden-gate/internal/specgen"
+)
+
+func runOpenAPI(args []string) error {
+	fs := newFlagSet("openapi")
+	var instance instanceFlags
+	instance.register(fs)
+	configPath := fs.String("config", config.GetConfigPath(), "path to the config file, used to look up the service target")
+	service := fs.String("service", "", "service to describe (required)")
+	captureFile := fs.String("capture", "", "read captures from this file instead of a running instance")
+	format := fs.String("format", "yaml", "output format: yaml or json")
+	output := fs.String("o", "", "write the document here instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *service == "" {
+		return errors.New("-service is required")
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	svc, ok := cfg.Services[*service]
+	if !ok {
+		return fmt.Errorf("service %q is not in %s", *service, *configPath)
+	}
+
+	var file *capture.File
+	if *captureFile != "" {
+		file, err = capture.ReadFile(*captureFile)
+	} else {
+		var resp *http.Response
+		resp, err = instance.do(http.MethodGet, "/api/admin/captures", nil)
+		if err == nil {
+			defer resp.Body.Close()
+			file, err = capture.Read(resp.Body)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	doc, err := specgen.Generate(*service, svc.Target, file.Requests)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch *format {
+	case "yaml":
+		data, err = specgen.MarshalYAML(doc)
+	case "json":
+		data, err = specgen.MarshalJSON(doc)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0o644)
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+// runHashPassword prints the bcrypt hash of a password read from stdin, for
+// the password_hash of an auth user.
+func runHashPassword(args []string) error {
+	fs := newFlagSet("hash-password")
+	cost := fs.Int("cost", bcrypt.DefaultCost, "bcrypt cost")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return fmt.Errorf("reading the password: %w", err)
+	}
+	if password == "" {
+		return errors.New("the password is empty")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), *cost)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(hash))
+	return nil
+}
+
+// readPassword prompts for the password without echoing it when stdin is a
+// terminal, and reads the first line otherwise, e.g. from a pipe.
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Password: ")
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/capture"
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+// hopHeaders are not replayed because they describe the original connection.
+var hopHeaders = []string{
+	"Connection", "Content-Length", "Keep-Alive", "Proxy-Connection",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+func runReplay(args []string) error {
+	fs := newFlagSet("replay")
+	target := fs.String("target", "", "base URL to send the requests to (required)")
+	incoming := fs.Bool("incoming", false, "replay the incoming path instead of the upstream one, e.g. to go through another Golden Gate")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout per request")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *target == "" || fs.NArg() != 1 {
+		return errors.New("usage: golden-gate replay -target <url> <capture file>")
+	}
+
+	base, err := url.Parse(*target)
+	if err != nil {
+		return err
+	}
+
+	file, err := capture.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	var failed int
+	for _, logged := range file.Requests {
+		req, err := replayRequest(base, logged, *incoming)
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			failed++
+			fmt.Printf("%-7s %s -> error: %v\n", req.Method, req.URL, err)
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		note := ""
+		if logged.Response != nil && logged.Response.StatusCode != resp.StatusCode {
+			failed++
+			note = fmt.Sprintf(" (recorded %d)", logged.Response.StatusCode)
+		}
+		fmt.Printf("%-7s %s -> %d in %s%s\n", req.Method, req.URL, resp.StatusCode, time.Since(start).Round(time.Millisecond), note)
+	}
+
+	fmt.Printf("replayed %d requests, %d differed or failed\n", len(file.Requests), failed)
+	if failed > 0 {
+		return fmt.Errorf("%d requests differed or failed", failed)
+	}
+	return nil
+}
+
+// replayRequest rebuilds a captured request against base, keeping the path
+// and query of the original.
+func replayRequest(base *url.URL, logged *types.RequestLog, incoming bool) (*http.Request, error) {
+	source := logged.URL
+	if incoming && logged.IncomingURL != "" {
+		source = logged.IncomingURL
+	}
+	original, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	u := *base
+	u.Path = strings.TrimSuffix(base.Path, "/") + original.Path
+	u.RawPath = ""
+	if original.RawPath != "" {
+		u.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + original.RawPath
+	}
+	u.RawQuery = original.RawQuery
+
+	req, err := http.NewRequest(logged.Method, u.String(), bytes.NewReader(logged.Body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range logged.Headers {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	for _, key := range hopHeaders {
+		req.Header.Del(key)
+	}
+	return req, nil
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/capture"
+	"github.com/mtavano/golden-gate/internal/config"
+	"github.com/mtavano/golden-gate/internal/logging"
+	"github.com/mtavano/golden-gate/internal/server"
+	"github.com/mtavano/golden-gate/internal/tracing"
+	"github.com/mtavano/golden-gate/internal/tui"
+	"github.com/mtavano/golden-gate/internal/types"
+	"go.uber.org/zap"
+)
+
+func runServe(args []string) error {
+	return serve("serve", args, false)
+}
+
+// runTUI serves like runServe and shows the captures in the terminal. Log
+// outputs to the terminal are turned off so they do not garble the screen.
+func runTUI(args []string) error {
+	return serve("tui", args, true)
+}
+
+func serve(name string, args []string, withTUI bool) error {
+	fs := newFlagSet(name)
+	configPath := fs.String("config", config.GetConfigPath(), "path to the config file")
+	listen := fs.String("listen", "", "listen address, overrides server.listen")
+	storeSize := fs.Int("store-size", 0, "number of requests to keep, overrides store.max_requests")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	override := func(cfg *config.Config) {
+		if *listen != "" {
+			cfg.Server.Listen = *listen
+		}
+		if *storeSize > 0 {
+			cfg.Store.MaxRequests = *storeSize
+		}
+	}
+	override(cfg)
+
+	// Install the application logger and access log
+	logs := cfg.Logging
+	if withTUI {
+		logs = withoutTerminal(logs)
+	}
+	closeLogs := logging.Setup(logs)
+	defer closeLogs()
+
+	// Create the request store, restoring the previous run if persisted
+	requestStore := types.NewRequestStore(cfg.Store.MaxRequests)
+	if err := restoreStore(cfg.Store.PersistPath, requestStore); err != nil {
+		return err
+	}
+
+	// Export spans when a collector is configured
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+
+	// Build the router
+	srv, err := server.New(*configPath, cfg, requestStore)
+	if err != nil {
+		return err
+	}
+	srv.Override(override)
+
+	// Stop on SIGINT/SIGTERM; a second signal kills the process right away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// Reload on config file changes and SIGHUP
+	go func() {
+		if err := srv.Watch(ctx); err != nil {
+			zap.L().Warn("config hot reload disabled", zap.Error(err))
+		}
+	}()
+
+	// Evaluate alert rules
+	go srv.RunAlerts(ctx)
+
+	// Start the server
+	zap.L().Info("starting server", zap.String("listen", cfg.Server.Listen))
+	if withTUI {
+		err = serveTUI(ctx, stop, srv, cfg, requestStore)
+	} else {
+		err = srv.ListenAndServe(ctx, cfg.Server.Listen, time.Duration(cfg.Server.ShutdownTimeout))
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	// Flush captures once nothing is in flight anymore
+	if flushErr := flushStore(cfg.Store.PersistPath, requestStore); flushErr != nil {
+		err = errors.Join(err, flushErr)
+	}
+
+	// Send the spans of the last requests before exiting
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if tracingErr := shutdownTracing(flushCtx); tracingErr != nil {
+		zap.L().Error("failed to flush traces", zap.Error(tracingErr))
+	}
+
+	zap.L().Info("server stopped")
+	return err
+}
+
+// serveTUI runs the server in the background while the terminal UI is shown.
+// Quitting the UI shuts the server down, and so does a server error.
+func serveTUI(ctx context.Context, stop context.CancelFunc, srv *server.Server, cfg *config.Config, requestStore *types.RequestStore) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.ListenAndServe(ctx, cfg.Server.Listen, time.Duration(cfg.Server.ShutdownTimeout))
+		stop()
+	}()
+
+	uiErr := tui.Run(ctx, requestStore, "listening on "+cfg.Server.Listen)
+	stop()
+	return errors.Join(<-done, uiErr)
+}
+
+// withoutTerminal drops the log outputs that write to stdout or stderr.
+func withoutTerminal(cfg config.LoggingConfig) config.LoggingConfig {
+	onTerminal := func(output config.LogOutput) bool {
+		return output.Type != "file"
+	}
+
+	outputs := make([]config.LogOutput, 0, len(cfg.Outputs))
+	for _, output := range cfg.Outputs {
+		if !onTerminal(output) {
+			outputs = append(outputs, output)
+		}
+	}
+	cfg.Outputs = outputs
+	if cfg.AccessLog != nil && onTerminal(*cfg.AccessLog.Output) {
+		cfg.AccessLog = nil
+	}
+	return cfg
+}
+
+func restoreStore(path string, requestStore *types.RequestStore) error {
+	if path == "" {
+		return nil
+	}
+
+	file, err := capture.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, req := range file.Requests {
+		requestStore.AddRequest(req)
+	}
+	zap.L().Info("captures restored", zap.Int("count", len(file.Requests)), zap.String("path", path))
+	return nil
+}
+
+func flushStore(path string, requestStore *types.RequestStore) error {
+	if path == "" {
+		return nil
+	}
+
+	requests := requestStore.GetRequests()
+	if err := capture.WriteFile(path, requests); err != nil {
+		return err
+	}
+	zap.L().Info("captures flushed", zap.Int("count", len(requests)), zap.String("path", path))
+	return nil
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/snippet"
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+func runTail(args []string) error {
+	fs := newFlagSet("tail")
+	var instance instanceFlags
+	instance.register(fs)
+	service := fs.String("service", "", "only show these services, comma separated")
+	method := fs.String("method", "", "only show these methods, comma separated")
+	status := fs.String("status", "", `only show this status, like "404", "5xx" or "400-499"`)
+	path := fs.String("path", "", "only show URLs containing this text")
+	last := fs.Int("n", 0, "first print this many of the exchanges already captured")
+	format := fs.String("format", "summary", "output format: summary, json or curl")
+	omitSecrets := fs.Bool("omit-secrets", false, "leave credential headers out of curl commands")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var print func(line []byte, req *types.RequestLog) error
+	switch *format {
+	case "summary":
+		print = func(_ []byte, req *types.RequestLog) error {
+			_, err := fmt.Println(summary(req))
+			return err
+		}
+	case "json":
+		print = func(line []byte, _ *types.RequestLog) error {
+			_, err := os.Stdout.Write(line)
+			return err
+		}
+	case "curl":
+		print = func(_ []byte, req *types.RequestLog) error {
+			code, err := snippet.Generate(snippet.Curl, req, snippet.Options{OmitSecrets: *omitSecrets})
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Printf("# %s\n%s\n\n", summary(req), code)
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format %q, expected summary, json or curl", *format)
+	}
+
+	query := url.Values{}
+	for _, name := range strings.Split(*service, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			query.Add("service", name)
+		}
+	}
+	for _, name := range strings.Split(*method, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			query.Add("method", name)
+		}
+	}
+	if *status != "" {
+		query.Set("status", *status)
+	}
+	if *path != "" {
+		query.Set("path", *path)
+	}
+	if *last > 0 {
+		query.Set("last", strconv.Itoa(*last))
+	}
+
+	// Stop quietly on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	resp, err := instance.doContext(ctx, http.MethodGet, "/api/admin/captures/stream?"+query.Encode(), nil)
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			return errors.New("the instance closed the stream")
+		}
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var req types.RequestLog
+		if err := json.Unmarshal(line, &req); err != nil {
+			return fmt.Errorf("reading stream: %w", err)
+		}
+		if err := print(line, &req); err != nil {
+			return err
+		}
+	}
+}
+
+// summary describes an exchange on one line, like
+// "15:04:05.000 GET     200 12ms   buda /buda/orders/1 01M58...".
+func summary(req *types.RequestLog) string {
+	status := "ERR"
+	if req.Response != nil {
+		status = strconv.Itoa(req.Response.StatusCode)
+	}
+
+	target := req.IncomingURL
+	if target == "" {
+		target = req.URL
+	}
+	if u, err := url.Parse(target); err == nil && u.Path != "" {
+		target = u.RequestURI()
+	}
+
+	line := fmt.Sprintf("%s %-7s %s %-8s %s %s %s",
+		req.Timestamp.Local().Format("15:04:05.000"),
+		req.Method,
+		status,
+		req.Duration.Round(100*time.Microsecond),
+		orDash(req.Service),
+		target,
+		req.ID,
+	)
+	if req.Error != "" {
+		line += " error: " + req.Error
+	}
+	return line
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
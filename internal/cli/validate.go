@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/mtavano/golden-gate/internal/config"
+)
+
+func runValidate(args []string) error {
+	fs := newFlagSet("validate")
+	configPath := fs.String("config", config.GetConfigPath(), "path to the config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("%s is invalid:\n%w", *configPath, err)
+	}
+
+	fmt.Printf("%s is valid (%d services)\n", *configPath, len(cfg.Services))
+	return nil
+}
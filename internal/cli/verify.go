@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+
+	"github.com/mtavano/golden-gate/internal/config"
+	"github.com/mtavano/golden-gate/internal/golden"
+)
+
+func runVerify(args []string) error {
+	fs := newFlagSet("verify")
+	update := fs.Bool("update", false, "record the live responses as the new golden files")
+	target := fs.String("target", "", "base URL to send the requests to, overrides the suite's target")
+	run := fs.String("run", "", "only run the requests whose name matches this regular expression")
+	format := fs.String("format", "text", "report format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: golden-gate verify [-update] [-target <url>] <suite file>")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q, expected text or json", *format)
+	}
+	var only *regexp.Regexp
+	if *run != "" {
+		var err error
+		if only, err = regexp.Compile(*run); err != nil {
+			return fmt.Errorf("-run: %w", err)
+		}
+	}
+
+	suite, err := config.LoadSuite(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	runner, err := golden.NewRunner(suite, *target)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var results []golden.Result
+	counts := map[string]int{}
+	for _, req := range suite.Requests {
+		if only != nil && !only.MatchString(req.Name) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		result := runner.Run(ctx, req, *update)
+		results = append(results, result)
+		counts[result.Status]++
+		if *format == "text" {
+			printResult(result)
+		}
+	}
+
+	if len(results) == 0 {
+		return fmt.Errorf("no request matches -run %q", *run)
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else if *update {
+		fmt.Printf("recorded %d golden files in %s, %d failed\n", counts[golden.StatusRecorded], suite.Dir, counts[golden.StatusError])
+	} else {
+		fmt.Printf("verified %d requests: %d ok, %d differed, %d failed\n", len(results), counts[golden.StatusOK], counts[golden.StatusFailed], counts[golden.StatusError])
+	}
+
+	if bad := counts[golden.StatusFailed] + counts[golden.StatusError]; bad > 0 {
+		return fmt.Errorf("%d requests differed or failed", bad)
+	}
+	return nil
+}
+
+func printResult(result golden.Result) {
+	label := map[string]string{
+		golden.StatusOK:       "ok",
+		golden.StatusFailed:   "FAIL",
+		golden.StatusRecorded: "recorded",
+		golden.StatusError:    "ERROR",
+	}[result.Status]
+	fmt.Printf("%-8s %s %s %s\n", label, result.Name, result.Method, result.Path)
+	if result.Error != "" {
+		fmt.Printf("         %s\n", result.Error)
+	}
+	for _, diff := range result.Diffs {
+		fmt.Printf("         %s: expected %s, got %s\n", diff.Path, diff.Expected, diff.Actual)
+	}
+}
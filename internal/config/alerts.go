@@ -0,0 +1,204 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/filter"
+)
+
+// AlertsConfig evaluates rules over the captured traffic and notifies
+// webhooks when they start firing and when they resolve.
+type AlertsConfig struct {
+	// Interval is how often the rules are evaluated.
+	Interval Duration    `json:"interval,omitempty"`
+	Rules    []AlertRule `json:"rules,omitempty"`
+	Webhooks []Webhook   `json:"webhooks,omitempty"`
+}
+
+// AlertRule fires while Condition holds for the requests matching Match that
+// completed within the last Window, e.g.
+//
+//	{"name": "buda-errors", "match": "service=buda status=5xx",
+//	 "condition": "count > 5", "window": "1m"}
+type AlertRule struct {
+	Name string `json:"name"`
+	// Match uses the terminal UI filter syntax, like "service=buda
+	// method=post". Empty matches every request.
+	Match string `json:"match,omitempty"`
+	// Condition is "<metric> <op> <threshold>". Metrics are count,
+	// error_rate (like "5%" or 0.05), and p50, p90, p95 and p99 latency
+	// (like "2s"); operators are >, >=, < and <=.
+	Condition string   `json:"condition"`
+	Window    Duration `json:"window,omitempty"`
+	// MinRequests keeps rate and latency rules quiet until the window holds
+	// enough requests to mean something.
+	MinRequests int `json:"min_requests,omitempty"`
+	// Webhooks names the webhooks notified; all of them when empty.
+	Webhooks []string `json:"webhooks,omitempty"`
+}
+
+// Webhook receives a JSON POST whenever an alert fires or resolves. The
+// slack format sends {"text": ...} for Slack and compatible incoming
+// webhooks.
+type Webhook struct {
+	Name    string            `json:"name"`
+	URL     string            `json:"url"`
+	Format  string            `json:"format,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// AlertCondition is a parsed AlertRule.Condition. Latency thresholds are in
+// seconds and error rates are a fraction.
+type AlertCondition struct {
+	Metric    string
+	Op        string
+	Threshold float64
+}
+
+// ParseAlertCondition reads a condition like "p95 > 2s".
+func ParseAlertCondition(s string) (AlertCondition, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return AlertCondition{}, fmt.Errorf("%q is not like \"count > 5\"", s)
+	}
+	cond := AlertCondition{Metric: fields[0], Op: fields[1]}
+	switch cond.Op {
+	case ">", ">=", "<", "<=":
+	default:
+		return AlertCondition{}, fmt.Errorf("unknown operator %q, expected >, >=, < or <=", cond.Op)
+	}
+
+	value := fields[2]
+	var err error
+	switch cond.Metric {
+	case "count":
+		var n int
+		n, err = strconv.Atoi(value)
+		cond.Threshold = float64(n)
+	case "error_rate":
+		if percent, ok := strings.CutSuffix(value, "%"); ok {
+			cond.Threshold, err = strconv.ParseFloat(percent, 64)
+			cond.Threshold /= 100
+		} else {
+			cond.Threshold, err = strconv.ParseFloat(value, 64)
+		}
+		if err == nil && (cond.Threshold < 0 || cond.Threshold > 1) {
+			return AlertCondition{}, fmt.Errorf("error rate %s is not between 0 and 100%%", value)
+		}
+	case "p50", "p90", "p95", "p99":
+		var d time.Duration
+		d, err = time.ParseDuration(value)
+		cond.Threshold = d.Seconds()
+	default:
+		return AlertCondition{}, fmt.Errorf("unknown metric %q, expected count, error_rate, p50, p90, p95 or p99", cond.Metric)
+	}
+	if err != nil {
+		return AlertCondition{}, fmt.Errorf("threshold %q: %w", value, err)
+	}
+	return cond, nil
+}
+
+// Holds reports whether value meets the condition.
+func (c AlertCondition) Holds(value float64) bool {
+	switch c.Op {
+	case ">":
+		return value > c.Threshold
+	case ">=":
+		return value >= c.Threshold
+	case "<":
+		return value < c.Threshold
+	default:
+		return value <= c.Threshold
+	}
+}
+
+// Format writes value in the unit of the condition's metric.
+func (c AlertCondition) Format(value float64) string {
+	switch c.Metric {
+	case "count":
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case "error_rate":
+		return strconv.FormatFloat(value*100, 'f', 1, 64) + "%"
+	default:
+		return time.Duration(value * float64(time.Second)).Round(time.Millisecond).String()
+	}
+}
+
+func (a *AlertsConfig) applyDefaults() {
+	if a.Interval == 0 {
+		a.Interval = Duration(10 * time.Second)
+	}
+	for i := range a.Rules {
+		if a.Rules[i].Window == 0 {
+			a.Rules[i].Window = Duration(time.Minute)
+		}
+		if a.Rules[i].MinRequests == 0 {
+			a.Rules[i].MinRequests = 1
+		}
+	}
+	for i := range a.Webhooks {
+		if a.Webhooks[i].Format == "" {
+			a.Webhooks[i].Format = "json"
+		}
+	}
+}
+
+func validateAlerts(alerts AlertsConfig, fail func(path, format string, args ...any)) {
+	if alerts.Interval <= 0 {
+		fail("alerts.interval", "must be positive")
+	}
+
+	webhooks := map[string]bool{}
+	for i, webhook := range alerts.Webhooks {
+		path := fmt.Sprintf("alerts.webhooks[%d]", i)
+		if webhook.Name == "" {
+			fail(path+".name", "required")
+		} else if webhooks[webhook.Name] {
+			fail(path+".name", "%q is used twice", webhook.Name)
+		}
+		webhooks[webhook.Name] = true
+		target, err := url.Parse(webhook.URL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			fail(path+".url", "%q is not an http(s) URL", webhook.URL)
+		}
+		switch webhook.Format {
+		case "json", "slack":
+		default:
+			fail(path+".format", "unknown format %q, expected json or slack", webhook.Format)
+		}
+	}
+
+	rules := map[string]bool{}
+	for i, rule := range alerts.Rules {
+		path := fmt.Sprintf("alerts.rules[%d]", i)
+		if rule.Name == "" {
+			fail(path+".name", "required")
+		} else if rules[rule.Name] {
+			fail(path+".name", "%q is used twice", rule.Name)
+		}
+		rules[rule.Name] = true
+		if f, err := filter.ParseTerms(rule.Match); err != nil {
+			fail(path+".match", "%v", err)
+		} else if f.From != "" || f.To != "" {
+			fail(path+".match", "from and to are not allowed, the window sets the time range")
+		}
+		if _, err := ParseAlertCondition(rule.Condition); err != nil {
+			fail(path+".condition", "%v", err)
+		}
+		if rule.Window <= 0 {
+			fail(path+".window", "must be positive")
+		}
+		if rule.MinRequests < 0 {
+			fail(path+".min_requests", "must be positive")
+		}
+		for j, name := range rule.Webhooks {
+			if !webhooks[name] {
+				fail(fmt.Sprintf("%s.webhooks[%d]", path, j), "unknown webhook %q", name)
+			}
+		}
+	}
+}
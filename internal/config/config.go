@@ -1,34 +1,525 @@
 package config
 
 import (
-	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 )
 
+// CurrentVersion is the config schema version understood by this build.
+// Files without a version key are read as the legacy flat service map.
+const CurrentVersion = 1
+
+type Config struct {
+	Version   int                      `json:"version"`
+	Server    ServerConfig             `json:"server,omitempty"`
+	Store     StoreConfig              `json:"store,omitempty"`
+	Logging   LoggingConfig            `json:"logging,omitempty"`
+	Dashboard DashboardConfig          `json:"dashboard,omitempty"`
+	Tracing   TracingConfig            `json:"tracing,omitempty"`
+	Auth      AuthConfig               `json:"auth,omitempty"`
+	Alerts    AlertsConfig             `json:"alerts,omitempty"`
+	Services  map[string]ServiceConfig `json:"services"`
+
+	// legacy is set when the main file used the unversioned format, so it
+	// is written back the same way.
+	legacy bool
+	// sources maps each service to the file it was loaded from.
+	sources map[string]string
+	// loaded holds the services as they were loaded, so unchanged ones are
+	// written back as they are in their file.
+	loaded map[string]ServiceConfig
+}
+
+type ServerConfig struct {
+	Listen string `json:"listen,omitempty"`
+	// ShutdownTimeout bounds how long in-flight requests are drained on
+	// SIGINT/SIGTERM.
+	ShutdownTimeout Duration `json:"shutdown_timeout,omitempty"`
+	// RequestIDHeader carries each capture's id to the upstream, replacing
+	// any value the client set. "-" disables it.
+	RequestIDHeader string `json:"request_id_header,omitempty"`
+}
+
+type StoreConfig struct {
+	MaxRequests int `json:"max_requests,omitempty"`
+	// PersistPath, when set, is a capture file the store is loaded from on
+	// start and flushed to on shutdown.
+	PersistPath string `json:"persist_path,omitempty"`
+}
+
+type LoggingConfig struct {
+	Level string `json:"level,omitempty"`
+	// Format is json (one object per line, the default), pretty, logfmt or
+	// console.
+	Format    string           `json:"format,omitempty"`
+	Outputs   []LogOutput      `json:"outputs,omitempty"`
+	Sampling  *LogSampling     `json:"sampling,omitempty"`
+	AccessLog *AccessLogConfig `json:"access_log,omitempty"`
+}
+
+// LogOutput is where log lines are written: stdout, stderr or a file.
+// Files are rotated when they reach MaxSizeMB or every RotateEvery.
+type LogOutput struct {
+	Type        string   `json:"type"`
+	Path        string   `json:"path,omitempty"`
+	MaxSizeMB   int      `json:"max_size_mb,omitempty"`
+	RotateEvery Duration `json:"rotate_every,omitempty"`
+	MaxBackups  int      `json:"max_backups,omitempty"`
+	MaxAgeDays  int      `json:"max_age_days,omitempty"`
+	Compress    bool     `json:"compress,omitempty"`
+}
+
+// LogSampling keeps the first Initial entries with the same message every
+// Tick, then one in Thereafter.
+type LogSampling struct {
+	Initial    int      `json:"initial"`
+	Thereafter int      `json:"thereafter"`
+	Tick       Duration `json:"tick,omitempty"`
+}
+
+// AccessLogConfig writes one Common or Combined Log Format line per proxied
+// request, separately from the application log.
+type AccessLogConfig struct {
+	Format string     `json:"format,omitempty"`
+	Output *LogOutput `json:"output,omitempty"`
+}
+
+type DashboardConfig struct {
+	Enabled *bool  `json:"enabled,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
+// TracingConfig exports OpenTelemetry spans over OTLP/HTTP. Tracing is off
+// when Endpoint is empty.
+type TracingConfig struct {
+	// Endpoint is the collector base URL, e.g. http://localhost:4318.
+	Endpoint    string            `json:"endpoint,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	ServiceName string            `json:"service_name,omitempty"`
+	SampleRatio *float64          `json:"sample_ratio,omitempty"`
+	// TraceURL links trace ids in the dashboard to a tracing UI, with
+	// {trace_id} replaced, e.g. http://localhost:16686/trace/{trace_id}.
+	TraceURL string `json:"trace_url,omitempty"`
+}
+
+// TracingEnabled reports whether spans should be exported.
+func (c *Config) TracingEnabled() bool {
+	return c.Tracing.Endpoint != ""
+}
+
+// AuthConfig protects the dashboard and the admin API. Without users, tokens
+// or OIDC the dashboard is open and the admin API only accepts the
+// GOLDEN_GATE_ADMIN_TOKEN token.
+type AuthConfig struct {
+	Users  []AuthUser  `json:"users,omitempty"`
+	Tokens []AuthToken `json:"tokens,omitempty"`
+	OIDC   *OIDCConfig `json:"oidc,omitempty"`
+	// SessionSecret signs dashboard session cookies. A random secret is
+	// used when it is empty, so sessions end when the process restarts.
+	SessionSecret string   `json:"session_secret,omitempty"`
+	SessionTTL    Duration `json:"session_ttl,omitempty"`
+}
+
+// AuthUser signs in with a password, checked against a bcrypt hash.
+type AuthUser struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	Role         string `json:"role"`
+}
+
+// AuthToken is a bearer token for scripts and the CLI.
+type AuthToken struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	Role  string `json:"role"`
+}
+
+// OIDCConfig signs dashboard users in with an OpenID Connect provider using
+// the authorization code flow.
+type OIDCConfig struct {
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	// RedirectURL defaults to the dashboard's /oidc/callback on the host
+	// the login started from.
+	RedirectURL string `json:"redirect_url,omitempty"`
+	// RoleClaim names the ID token claim, a string or a list, whose values
+	// are looked up in Roles. The highest matching role wins and
+	// DefaultRole applies when none match; without it the user is refused.
+	RoleClaim   string            `json:"role_claim,omitempty"`
+	Roles       map[string]string `json:"roles,omitempty"`
+	DefaultRole string            `json:"default_role,omitempty"`
+}
+
+// AuthEnabled reports whether the dashboard requires signing in.
+func (c *Config) AuthEnabled() bool {
+	return len(c.Auth.Users) > 0 || len(c.Auth.Tokens) > 0 || c.Auth.OIDC != nil
+}
+
 type ServiceConfig struct {
-	BasePrefix string `json:"base_prefix"`
-	Target     string `json:"target"`
+	BasePrefix string         `json:"base_prefix"`
+	Target     string         `json:"target"`
+	Rewrite    *RewriteConfig `json:"rewrite,omitempty"`
+	Mirror     *MirrorConfig  `json:"mirror,omitempty"`
+	// OpenAPI is the path of an OpenAPI 3 document, relative to the config
+	// file, that proxied traffic is validated against.
+	OpenAPI  string `json:"openapi,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
 }
 
-type Config struct {
-	Services map[string]ServiceConfig `json:"-"`
+// MirrorConfig sends a copy of every request to a second target. Its
+// responses never reach the client; they are diffed against the primary.
+type MirrorConfig struct {
+	Target        string   `json:"target"`
+	Timeout       Duration `json:"timeout,omitempty"`
+	IgnoreHeaders []string `json:"ignore_headers,omitempty"`
 }
 
-func LoadConfig(configPath string) (*Config, error) {
-	file, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
+// RewriteConfig describes how an incoming path and query are turned into the
+// upstream URL. The zero value strips BasePrefix and forwards everything else
+// untouched.
+type RewriteConfig struct {
+	StripPrefix *bool         `json:"strip_prefix,omitempty"`
+	Rules       []RewriteRule `json:"rules,omitempty"`
+	Query       QueryRewrite  `json:"query,omitempty"`
+}
 
-	var config Config
-	if err := json.Unmarshal(file, &config.Services); err != nil {
-		return nil, err
+// RewriteRule is either a regex rule (Match/Replace, with $1 or ${name}
+// capture references) or a template rule (From/To, e.g. "/v1/users/{id}" to
+// "/users/{id}/profile").
+type RewriteRule struct {
+	Match   string `json:"match,omitempty"`
+	Replace string `json:"replace,omitempty"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+}
+
+type QueryRewrite struct {
+	Add    map[string]string `json:"add,omitempty"`
+	Remove []string          `json:"remove,omitempty"`
+	Rename map[string]string `json:"rename,omitempty"`
+}
+
+// ValidationError collects every problem found in a config.
+type ValidationError struct {
+	Errs []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
 	}
+	return strings.Join(msgs, "\n")
+}
 
-	return &config, nil
+func (e *ValidationError) Unwrap() []error {
+	return e.Errs
 }
 
+// ConfigPathEnv overrides the default config path.
+const ConfigPathEnv = "GOLDEN_GATE_CONFIG"
+
 func GetConfigPath() string {
+	if path := os.Getenv(ConfigPathEnv); path != "" {
+		return path
+	}
 	return filepath.Join("configs", "service.json")
-} 
\ No newline at end of file
+}
+
+// DashboardEnabled reports whether the dashboard should be served.
+func (c *Config) DashboardEnabled() bool {
+	return c.Dashboard.Enabled == nil || *c.Dashboard.Enabled
+}
+
+func (c *Config) applyDefaults() {
+	if c.Server.Listen == "" {
+		c.Server.Listen = ":8080"
+	}
+	if c.Server.ShutdownTimeout == 0 {
+		c.Server.ShutdownTimeout = Duration(30 * time.Second)
+	}
+	if c.Server.RequestIDHeader == "" {
+		c.Server.RequestIDHeader = "X-Request-ID"
+	}
+	if c.Store.MaxRequests == 0 {
+		c.Store.MaxRequests = 100
+	}
+	if c.Logging.Level == "" {
+		c.Logging.Level = "info"
+	}
+	if c.Logging.Format == "" {
+		c.Logging.Format = "json"
+	}
+	if len(c.Logging.Outputs) == 0 {
+		c.Logging.Outputs = []LogOutput{{Type: "stdout"}}
+	}
+	if c.Logging.Sampling != nil && c.Logging.Sampling.Tick == 0 {
+		c.Logging.Sampling.Tick = Duration(time.Second)
+	}
+	if c.Logging.AccessLog != nil {
+		if c.Logging.AccessLog.Format == "" {
+			c.Logging.AccessLog.Format = "common"
+		}
+		if c.Logging.AccessLog.Output == nil {
+			c.Logging.AccessLog.Output = &LogOutput{Type: "stdout"}
+		}
+	}
+	if c.Dashboard.Path == "" {
+		c.Dashboard.Path = "/dashboard"
+	}
+	if c.Tracing.ServiceName == "" {
+		c.Tracing.ServiceName = "golden-gate"
+	}
+	if c.Auth.SessionTTL == 0 {
+		c.Auth.SessionTTL = Duration(12 * time.Hour)
+	}
+	if oidc := c.Auth.OIDC; oidc != nil {
+		if len(oidc.Scopes) == 0 {
+			oidc.Scopes = []string{"openid", "profile", "email"}
+		}
+		if oidc.RoleClaim == "" {
+			oidc.RoleClaim = "groups"
+		}
+	}
+	c.Alerts.applyDefaults()
+	if c.Services == nil {
+		c.Services = map[string]ServiceConfig{}
+	}
+}
+
+// New returns a config for the given services with every other setting at
+// its default, for running without a config file. It is validated like a
+// loaded file.
+func New(services map[string]ServiceConfig) (*Config, error) {
+	config := &Config{Version: CurrentVersion, Services: services}
+	config.applyDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Clone returns a copy of the config whose service map can be modified
+// without affecting the original.
+func (c *Config) Clone() *Config {
+	clone := *c
+	clone.Services = make(map[string]ServiceConfig, len(c.Services))
+	for name, svc := range c.Services {
+		clone.Services[name] = svc
+	}
+	return &clone
+}
+
+// Validate checks the whole config and reports every problem at once, each
+// prefixed with the path of the offending field.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if !c.legacy && c.Version != CurrentVersion {
+		fail("version", "unsupported version %d, expected %d", c.Version, CurrentVersion)
+	}
+	if _, _, err := net.SplitHostPort(c.Server.Listen); err != nil {
+		fail("server.listen", "%v", err)
+	}
+	if c.Server.ShutdownTimeout < 0 {
+		fail("server.shutdown_timeout", "must be positive")
+	}
+	if c.Store.MaxRequests < 0 {
+		fail("store.max_requests", "must be positive")
+	}
+	switch c.Logging.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		fail("logging.level", "unknown level %q", c.Logging.Level)
+	}
+	switch c.Logging.Format {
+	case "json", "pretty", "logfmt", "console":
+	default:
+		fail("logging.format", "unknown format %q", c.Logging.Format)
+	}
+	for i, output := range c.Logging.Outputs {
+		validateLogOutput(fmt.Sprintf("logging.outputs[%d]", i), output, fail)
+	}
+	if sampling := c.Logging.Sampling; sampling != nil {
+		if sampling.Initial < 0 {
+			fail("logging.sampling.initial", "must be positive")
+		}
+		if sampling.Thereafter < 0 {
+			fail("logging.sampling.thereafter", "must be positive")
+		}
+	}
+	if accessLog := c.Logging.AccessLog; accessLog != nil {
+		switch accessLog.Format {
+		case "common", "combined":
+		default:
+			fail("logging.access_log.format", "unknown format %q, expected common or combined", accessLog.Format)
+		}
+		validateLogOutput("logging.access_log.output", *accessLog.Output, fail)
+	}
+	if !strings.HasPrefix(c.Dashboard.Path, "/") {
+		fail("dashboard.path", "must start with /")
+	}
+	if c.TracingEnabled() {
+		endpoint, err := url.Parse(c.Tracing.Endpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			fail("tracing.endpoint", "%q is not an http(s) URL", c.Tracing.Endpoint)
+		}
+	}
+	if ratio := c.Tracing.SampleRatio; ratio != nil && (*ratio < 0 || *ratio > 1) {
+		fail("tracing.sample_ratio", "must be between 0 and 1")
+	}
+	if c.Tracing.TraceURL != "" && !strings.Contains(c.Tracing.TraceURL, "{trace_id}") {
+		fail("tracing.trace_url", "must contain {trace_id}")
+	}
+	validateAuth(c.Auth, fail)
+	validateAlerts(c.Alerts, fail)
+
+	names := make([]string, 0, len(c.Services))
+	for name := range c.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	prefixes := map[string]string{}
+	for _, name := range names {
+		svc := c.Services[name]
+		path := "services." + name
+
+		if !strings.HasPrefix(svc.BasePrefix, "/") {
+			fail(path+".base_prefix", "must start with /")
+		} else if other, ok := prefixes[svc.BasePrefix]; ok {
+			fail(path+".base_prefix", "%q already used by %s", svc.BasePrefix, other)
+		} else if c.DashboardEnabled() && svc.BasePrefix == c.Dashboard.Path {
+			fail(path+".base_prefix", "%q collides with the dashboard path", svc.BasePrefix)
+		} else {
+			prefixes[svc.BasePrefix] = name
+		}
+
+		target, err := url.Parse(svc.Target)
+		if err != nil || target.Scheme == "" || target.Host == "" {
+			fail(path+".target", "%q is not an absolute URL", svc.Target)
+		}
+
+		if svc.Mirror != nil {
+			mirrorTarget, err := url.Parse(svc.Mirror.Target)
+			if err != nil || mirrorTarget.Scheme == "" || mirrorTarget.Host == "" {
+				fail(path+".mirror.target", "%q is not an absolute URL", svc.Mirror.Target)
+			}
+			if svc.Mirror.Timeout < 0 {
+				fail(path+".mirror.timeout", "must be positive")
+			}
+		}
+
+		if svc.Rewrite != nil {
+			for i, rule := range svc.Rewrite.Rules {
+				if rule.Match == "" {
+					continue
+				}
+				if _, err := regexp.Compile(rule.Match); err != nil {
+					fail(fmt.Sprintf("%s.rewrite.rules[%d].match", path, i), "%v", err)
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errs: errs}
+	}
+	return nil
+}
+
+func validateLogOutput(path string, output LogOutput, fail func(path, format string, args ...any)) {
+	switch output.Type {
+	case "stdout", "stderr":
+	case "file":
+		if output.Path == "" {
+			fail(path+".path", "required for file outputs")
+		}
+	default:
+		fail(path+".type", "unknown output %q, expected stdout, stderr or file", output.Type)
+	}
+	if output.MaxSizeMB < 0 {
+		fail(path+".max_size_mb", "must be positive")
+	}
+	if output.RotateEvery < 0 {
+		fail(path+".rotate_every", "must be positive")
+	}
+}
+
+var roles = map[string]bool{"viewer": true, "operator": true, "admin": true}
+
+func validateAuth(auth AuthConfig, fail func(path, format string, args ...any)) {
+	validateRole := func(path, role string) {
+		if !roles[role] {
+			fail(path, "unknown role %q, expected viewer, operator or admin", role)
+		}
+	}
+
+	usernames := map[string]bool{}
+	for i, user := range auth.Users {
+		path := fmt.Sprintf("auth.users[%d]", i)
+		if user.Username == "" {
+			fail(path+".username", "required")
+		} else if usernames[user.Username] {
+			fail(path+".username", "%q is used twice", user.Username)
+		}
+		usernames[user.Username] = true
+		if !strings.HasPrefix(user.PasswordHash, "$2") {
+			fail(path+".password_hash", "must be a bcrypt hash, see golden-gate hash-password")
+		}
+		validateRole(path+".role", user.Role)
+	}
+	for i, token := range auth.Tokens {
+		path := fmt.Sprintf("auth.tokens[%d]", i)
+		if token.Name == "" {
+			fail(path+".name", "required")
+		}
+		if len(token.Token) < 16 {
+			fail(path+".token", "must be at least 16 characters")
+		}
+		validateRole(path+".role", token.Role)
+	}
+	if auth.SessionSecret != "" && len(auth.SessionSecret) < 32 {
+		fail("auth.session_secret", "must be at least 32 characters")
+	}
+	if auth.SessionTTL < 0 {
+		fail("auth.session_ttl", "must be positive")
+	}
+	if oidc := auth.OIDC; oidc != nil {
+		issuer, err := url.Parse(oidc.Issuer)
+		if err != nil || (issuer.Scheme != "http" && issuer.Scheme != "https") || issuer.Host == "" {
+			fail("auth.oidc.issuer", "%q is not an http(s) URL", oidc.Issuer)
+		}
+		if oidc.ClientID == "" {
+			fail("auth.oidc.client_id", "required")
+		}
+		if oidc.RedirectURL != "" {
+			if redirect, err := url.Parse(oidc.RedirectURL); err != nil || redirect.Host == "" {
+				fail("auth.oidc.redirect_url", "%q is not an absolute URL", oidc.RedirectURL)
+			}
+		}
+		values := make([]string, 0, len(oidc.Roles))
+		for value := range oidc.Roles {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		for _, value := range values {
+			validateRole(fmt.Sprintf("auth.oidc.roles[%q]", value), oidc.Roles[value])
+		}
+		if oidc.DefaultRole != "" {
+			validateRole("auth.oidc.default_role", oidc.DefaultRole)
+		}
+	}
+}
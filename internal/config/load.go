@@ -0,0 +1,269 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// IncludeDir is the directory next to the main config file whose files are
+// merged into it. Included files may only define services.
+const IncludeDir = "conf.d"
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// fragment is the schema of a file in IncludeDir.
+type fragment struct {
+	Services map[string]ServiceConfig `json:"services"`
+}
+
+// LoadConfig reads the main config file, merges every file in IncludeDir and
+// validates the result. JSON, YAML and TOML are picked by file extension and
+// ${VAR} or ${VAR:-default} references are replaced from the environment.
+func LoadConfig(configPath string) (*Config, error) {
+	var errs []error
+
+	config := Config{sources: map[string]string{}}
+	doc, err := readDocument(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, versioned := doc["version"]; versioned {
+		errs = append(errs, decodeInto(configPath, doc, &config)...)
+	} else {
+		config.legacy = true
+		errs = append(errs, decodeInto(configPath, doc, &config.Services)...)
+	}
+	for name := range config.Services {
+		config.sources[name] = configPath
+	}
+
+	includes, err := IncludeFiles(configPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range includes {
+		doc, err := readDocument(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		var frag fragment
+		errs = append(errs, decodeInto(path, doc, &frag)...)
+		for name, svc := range frag.Services {
+			if other, ok := config.sources[name]; ok {
+				errs = append(errs, fmt.Errorf("%s: services.%s: already defined in %s", path, name, other))
+				continue
+			}
+			if config.Services == nil {
+				config.Services = map[string]ServiceConfig{}
+			}
+			config.Services[name] = svc
+			config.sources[name] = path
+		}
+	}
+
+	config.applyDefaults()
+	config.loaded = make(map[string]ServiceConfig, len(config.Services))
+	for name, svc := range config.Services {
+		config.loaded[name] = svc
+	}
+	var validationErr *ValidationError
+	if err := config.Validate(); errors.As(err, &validationErr) {
+		errs = append(errs, validationErr.Errs...)
+	}
+
+	if len(errs) > 0 {
+		return nil, &ValidationError{Errs: errs}
+	}
+
+	return &config, nil
+}
+
+// IncludeFiles lists the config files in the IncludeDir next to configPath,
+// in the order they are merged.
+func IncludeFiles(configPath string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(filepath.Dir(configPath), IncludeDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || !isConfigFile(entry.Name()) {
+			continue
+		}
+		files = append(files, filepath.Join(filepath.Dir(configPath), IncludeDir, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func isConfigFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".yaml", ".yml", ".toml":
+		return true
+	}
+	return false
+}
+
+// readDocument reads, interpolates and parses a config file into a generic
+// document.
+func readDocument(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = interpolate(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	doc, err := parseDocument(path, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc, nil
+}
+
+func parseDocument(path string, data []byte) (map[string]any, error) {
+	doc := map[string]any{}
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&doc)
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+	return doc, err
+}
+
+// interpolate replaces ${VAR} and ${VAR:-default} with values from the
+// environment. Unset variables without a default are reported together.
+func interpolate(data []byte) ([]byte, error) {
+	var missing []string
+	out := envPattern.ReplaceAllFunc(data, func(m []byte) []byte {
+		groups := envPattern.FindSubmatch(m)
+		if value, ok := os.LookupEnv(string(groups[1])); ok {
+			return []byte(value)
+		}
+		if groups[2] != nil {
+			return groups[3]
+		}
+		missing = append(missing, string(groups[1]))
+		return m
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// decodeInto reports unknown keys in doc and then decodes it into v.
+func decodeInto(path string, doc map[string]any, v any) []error {
+	var errs []error
+	checkKeys("", doc, reflect.TypeOf(v), func(field, msg string) {
+		errs = append(errs, fmt.Errorf("%s: %s: %s", path, field, msg))
+	})
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return append(errs, fmt.Errorf("%s: %w", path, err))
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(data, v); errors.As(err, &typeErr) {
+		errs = append(errs, fmt.Errorf("%s: %s: expected %s, got %s", path, typeErr.Field, typeErr.Type, typeErr.Value))
+	} else if err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", path, err))
+	}
+	return errs
+}
+
+// checkKeys walks a generic document alongside the Go type it will be decoded
+// into and reports every key that has no matching json field.
+func checkKeys(path string, v any, t reflect.Type, report func(field, msg string)) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+		fields := map[string]reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" || !field.IsExported() {
+				continue
+			}
+			fields[name] = field.Type
+		}
+		for _, key := range sortedKeys(m) {
+			fieldType, ok := fields[key]
+			if !ok {
+				report(joinField(path, key), "unknown key")
+				continue
+			}
+			checkKeys(joinField(path, key), m[key], fieldType, report)
+		}
+	case reflect.Map:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+		for _, key := range sortedKeys(m) {
+			checkKeys(joinField(path, key), m[key], t.Elem(), report)
+		}
+	case reflect.Slice:
+		items, ok := v.([]any)
+		if !ok {
+			return
+		}
+		for i, item := range items {
+			checkKeys(fmt.Sprintf("%s[%d]", path, i), item, t.Elem(), report)
+		}
+	}
+}
+
+func joinField(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes files, named relative to a new directory, and returns
+// the path of the first one.
+func writeConfig(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i := 0; i+1 < len(files); i += 2 {
+		path := filepath.Join(dir, files[i])
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(files[i+1]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, files[0])
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("GG_TEST_TARGET", "http://orders:8080")
+
+	tests := []struct {
+		name string
+		file string
+		data string
+		want string
+	}{
+		{"json", "config.json", `{"version": 1, "services": {"orders": {"base_prefix": "/orders", "target": "http://orders:8080"}}}`, "http://orders:8080"},
+		{"yaml", "config.yaml", "version: 1\nservices:\n  orders:\n    base_prefix: /orders\n    target: http://orders:8080\n", "http://orders:8080"},
+		{"toml", "config.toml", "version = 1\n[services.orders]\nbase_prefix = \"/orders\"\ntarget = \"http://orders:8080\"\n", "http://orders:8080"},
+		{"legacy", "config.json", `{"orders": {"base_prefix": "/orders", "target": "http://orders:8080"}}`, "http://orders:8080"},
+		{"environment", "config.yaml", "version: 1\nservices:\n  orders:\n    base_prefix: /orders\n    target: ${GG_TEST_TARGET}\n", "http://orders:8080"},
+		{"environment default", "config.yaml", "version: 1\nservices:\n  orders:\n    base_prefix: /orders\n    target: ${GG_TEST_UNSET:-http://localhost:9000}\n", "http://localhost:9000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfig(writeConfig(t, tt.file, tt.data))
+			if err != nil {
+				t.Fatalf("LoadConfig = %v", err)
+			}
+			if got := cfg.Services["orders"].Target; got != tt.want {
+				t.Errorf("target = %q, want %q", got, tt.want)
+			}
+			if cfg.Server.Listen != ":8080" || cfg.Store.MaxRequests != 100 {
+				t.Errorf("defaults not applied: listen %q, max_requests %d", cfg.Server.Listen, cfg.Store.MaxRequests)
+			}
+		})
+	}
+}
+
+func TestLoadConfigIncludes(t *testing.T) {
+	path := writeConfig(t,
+		"config.yaml", "version: 1\nservices:\n  orders:\n    base_prefix: /orders\n    target: http://orders:8080\n",
+		"conf.d/b.json", `{"services": {"users": {"base_prefix": "/users", "target": "http://users:8080"}}}`,
+		"conf.d/a.yaml", "services:\n  items:\n    base_prefix: /items\n    target: http://items:8080\n",
+		"conf.d/notes.txt", "not a config file",
+	)
+
+	includes, err := IncludeFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(filepath.Dir(path), IncludeDir)
+	if want := []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.json")}; strings.Join(includes, ",") != strings.Join(want, ",") {
+		t.Errorf("IncludeFiles = %v, want %v", includes, want)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig = %v", err)
+	}
+	sources := map[string]string{
+		"orders": path,
+		"items":  filepath.Join(dir, "a.yaml"),
+		"users":  filepath.Join(dir, "b.json"),
+	}
+	for name, source := range sources {
+		if _, ok := cfg.Services[name]; !ok {
+			t.Errorf("service %s not loaded", name)
+		}
+		if cfg.sources[name] != source {
+			t.Errorf("service %s loaded from %s, want %s", name, cfg.sources[name], source)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	const valid = "    base_prefix: /orders\n    target: http://orders:8080\n"
+
+	tests := []struct {
+		name     string
+		files    []string
+		wantErrs []string
+	}{
+		{"unset variable", []string{"config.yaml", "version: 1\nservices:\n  orders:\n    base_prefix: /orders\n    target: ${GG_TEST_UNSET}\n"},
+			[]string{"environment variables not set: GG_TEST_UNSET"}},
+		{"unknown key", []string{"config.yaml", "version: 1\nservices:\n  orders:\n" + valid + "    tagret: http://typo\n"},
+			[]string{"services.orders.tagret: unknown key"}},
+		{"wrong type", []string{"config.yaml", "version: 1\nstore:\n  max_requests: many\n"},
+			[]string{"store.max_requests: expected int"}},
+		{"unsupported version", []string{"config.yaml", "version: 2\n"},
+			[]string{"version: unsupported version 2"}},
+		{"every problem at once", []string{"config.yaml", "version: 1\nservices:\n  a:\n    base_prefix: orders\n    target: http://a\n  b:\n    base_prefix: /b\n    target: localhost\n"},
+			[]string{"services.a.base_prefix: must start with /", `services.b.target: "localhost" is not an absolute URL`}},
+		{"duplicate prefix", []string{"config.yaml", "version: 1\nservices:\n  a:\n" + valid + "  b:\n" + valid},
+			[]string{`services.b.base_prefix: "/orders" already used by a`}},
+		{"service defined twice", []string{
+			"config.yaml", "version: 1\nservices:\n  orders:\n" + valid,
+			"conf.d/orders.yaml", "services:\n  orders:\n    base_prefix: /other\n    target: http://orders:8080\n",
+		}, []string{"services.orders: already defined in"}},
+		{"include with other sections", []string{
+			"config.yaml", "version: 1\n",
+			"conf.d/server.yaml", "server:\n  listen: :9090\n",
+		}, []string{"server: unknown key"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tt.files...))
+			if err == nil {
+				t.Fatal("LoadConfig succeeded, want an error")
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("LoadConfig = %v, want an error containing %q", err, want)
+				}
+			}
+		})
+	}
+}
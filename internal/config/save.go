@@ -0,0 +1,226 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrEnvReference is returned by SaveConfig for a changed service whose
+// entry in its file references environment variables.
+var ErrEnvReference = errors.New("references environment variables")
+
+// SaveConfig writes the services of config back to the files they were
+// loaded from; new services go to configPath. Other sections, and services
+// that did not change since they were loaded, are preserved as written,
+// including ${VAR} references, but comments are not. A service with ${VAR}
+// references can be enabled or disabled, but other changes to it are refused
+// with ErrEnvReference, as they would write the variables' values to the
+// file. Files are replaced atomically so a concurrent reader never sees a
+// partial write.
+func SaveConfig(configPath string, config *Config) error {
+	files := map[string]map[string]ServiceConfig{configPath: {}}
+	for _, source := range config.sources {
+		files[source] = map[string]ServiceConfig{}
+	}
+	for name, svc := range config.Services {
+		source, ok := config.sources[name]
+		if !ok {
+			source = configPath
+		}
+		files[source][name] = svc
+	}
+
+	// Check every file before writing any, so a refused change leaves all
+	// of them as they were
+	docs := map[string]any{}
+	for path, services := range files {
+		legacy := path == configPath && config.legacy
+		doc, err := servicesDocument(path, services, config.loaded, legacy)
+		if err != nil {
+			return err
+		}
+		docs[path] = doc
+	}
+	for path, doc := range docs {
+		data, err := encodeDocument(path, doc)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(path, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// servicesDocument returns the document to write to path: its raw
+// (uninterpolated) content with the services replaced.
+func servicesDocument(path string, services, loaded map[string]ServiceConfig, legacy bool) (any, error) {
+	raw := map[string]any{}
+	if data, err := os.ReadFile(path); err == nil {
+		if raw, err = parseDocument(path, data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	rawServices := raw
+	if !legacy {
+		rawServices, _ = raw["services"].(map[string]any)
+	}
+
+	encoded := make(map[string]any, len(services))
+	for name, svc := range services {
+		rawService, inFile := rawServices[name]
+		previous, wasLoaded := loaded[name]
+		switch {
+		case inFile && wasLoaded && reflect.DeepEqual(svc, previous):
+			encoded[name] = rawService
+			continue
+		case inFile && referencesEnv(rawService):
+			entry, ok := rawService.(map[string]any)
+			previous.Disabled = svc.Disabled
+			if !ok || !wasLoaded || !reflect.DeepEqual(svc, previous) {
+				return nil, fmt.Errorf("%s: services.%s %w, edit it in the file instead", path, name, ErrEnvReference)
+			}
+			delete(entry, "disabled")
+			if svc.Disabled {
+				entry["disabled"] = true
+			}
+			encoded[name] = entry
+			continue
+		}
+
+		doc, err := toDocument(svc)
+		if err != nil {
+			return nil, err
+		}
+		encoded[name] = doc
+	}
+
+	if legacy {
+		return encoded, nil
+	}
+	raw["services"] = encoded
+	return raw, nil
+}
+
+// referencesEnv reports whether a raw document holds a ${VAR} reference.
+func referencesEnv(v any) bool {
+	switch v := v.(type) {
+	case string:
+		return envPattern.MatchString(v)
+	case map[string]any:
+		for _, item := range v {
+			if referencesEnv(item) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if referencesEnv(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toDocument converts v into the generic form the encoders expect, keeping
+// json field names and integer types.
+func toDocument(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc map[string]any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(doc).(map[string]any), nil
+}
+
+func normalizeNumbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			v[k] = normalizeNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+func encodeDocument(path string, doc any) ([]byte, error) {
+	var buf bytes.Buffer
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(normalizeNumbers(doc)); err != nil {
+			return nil, err
+		}
+	case ".toml":
+		if err := toml.NewEncoder(&buf).Encode(normalizeNumbers(doc)); err != nil {
+			return nil, err
+		}
+	default:
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(append(data, '\n'))
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFileAtomic replaces path with data, keeping the file's permissions.
+// CreateTemp makes files only the owner can read, which would otherwise
+// stick after the rename.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSaveConfigKeepsEnvReferences(t *testing.T) {
+	t.Setenv("UP", "http://secret:1")
+
+	tests := []struct {
+		name    string
+		change  func(cfg *Config)
+		wantErr error
+		want    []string
+	}{
+		{"other service added", func(cfg *Config) {
+			cfg.Services["orders"] = ServiceConfig{BasePrefix: "/orders", Target: "http://orders:8080"}
+		}, nil, []string{"${UP}", "http://orders:8080"}},
+		{"other service changed", func(cfg *Config) {
+			svc := cfg.Services["plain"]
+			svc.Target = "http://plain:9090"
+			cfg.Services["plain"] = svc
+		}, nil, []string{"${UP}", "http://plain:9090"}},
+		{"service disabled", func(cfg *Config) {
+			svc := cfg.Services["up"]
+			svc.Disabled = true
+			cfg.Services["up"] = svc
+		}, nil, []string{"${UP}", "disabled: true"}},
+		{"service changed", func(cfg *Config) {
+			svc := cfg.Services["up"]
+			svc.BasePrefix = "/other"
+			cfg.Services["up"] = svc
+		}, ErrEnvReference, []string{"${UP}", "base_prefix: /up"}},
+		{"service replaced", func(cfg *Config) {
+			delete(cfg.Services, "up")
+			cfg.Services["up"] = ServiceConfig{BasePrefix: "/up", Target: "http://secret:1"}
+		}, nil, []string{"${UP}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, "config.yaml", "version: 1\nservices:\n  up:\n    base_prefix: /up\n    target: ${UP}\n  plain:\n    base_prefix: /plain\n    target: http://plain:8080\n")
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.Services["up"].Target; got != "http://secret:1" {
+				t.Fatalf("loaded target = %q, want it interpolated", got)
+			}
+
+			tt.change(cfg)
+			if err := SaveConfig(path, cfg); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SaveConfig = %v, want %v", err, tt.wantErr)
+			}
+
+			saved, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(saved), "secret") {
+				t.Errorf("the saved file holds the value of UP:\n%s", saved)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(saved), want) {
+					t.Errorf("the saved file lacks %q:\n%s", want, saved)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Suite lists requests whose upstream responses golden-gate verify records
+// to golden files and later compares with the live ones. It is read from
+// JSON, YAML or TOML like the main config.
+type Suite struct {
+	// Target is the upstream base URL requests are sent to.
+	Target string `json:"target,omitempty"`
+	// Dir holds one golden file per request. Relative paths, like the
+	// default "golden", are resolved from the suite file's directory.
+	Dir     string   `json:"dir,omitempty"`
+	Timeout Duration `json:"timeout,omitempty"`
+	// Headers are sent with every request, e.g. credentials from ${VAR}.
+	// They are not written to the golden files.
+	Headers  map[string]string `json:"headers,omitempty"`
+	Ignore   IgnoreRules       `json:"ignore,omitempty"`
+	Requests []SuiteRequest    `json:"requests"`
+}
+
+// SuiteRequest is one request of a Suite. Its golden file is named after it.
+type SuiteRequest struct {
+	Name   string `json:"name"`
+	Method string `json:"method,omitempty"`
+	// Path is appended to the target path and may carry a query.
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is sent as JSON.
+	Body json.RawMessage `json:"body,omitempty"`
+	// Ignore adds to the suite's rules for this request.
+	Ignore IgnoreRules `json:"ignore,omitempty"`
+}
+
+// IgnoreRules leave parts of a response out of the comparison.
+type IgnoreRules struct {
+	// Headers are not recorded or compared.
+	Headers []string `json:"headers,omitempty"`
+	// Paths are JSON paths like "$.meta", "$.items[*].id" or
+	// "$..updated_at"; the value and everything below it is skipped.
+	Paths []string `json:"paths,omitempty"`
+	// Timestamps treats two strings that both parse as times as equal.
+	Timestamps bool `json:"timestamps,omitempty"`
+}
+
+var (
+	suiteNamePattern  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	methodPattern     = regexp.MustCompile(`^[A-Z]+$`)
+	ignorePathPattern = regexp.MustCompile(`^\$(\.\.?([^.\[\]]+)|\[(\*|\d+)\])*$`)
+)
+
+// LoadSuite reads and validates the suite file at path.
+func LoadSuite(path string) (*Suite, error) {
+	doc, err := readDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var suite Suite
+	if errs := decodeInto(path, doc, &suite); len(errs) > 0 {
+		return nil, &ValidationError{Errs: errs}
+	}
+	if suite.Dir == "" {
+		suite.Dir = "golden"
+	}
+	if !filepath.IsAbs(suite.Dir) {
+		suite.Dir = filepath.Join(filepath.Dir(path), suite.Dir)
+	}
+	if suite.Timeout == 0 {
+		suite.Timeout = Duration(30 * time.Second)
+	}
+	for i := range suite.Requests {
+		if suite.Requests[i].Method == "" {
+			suite.Requests[i].Method = "GET"
+		}
+	}
+
+	if err := suite.Validate(); err != nil {
+		return nil, err
+	}
+	return &suite, nil
+}
+
+// Validate checks the whole suite and reports every problem at once.
+func (s *Suite) Validate() error {
+	var errs []error
+	fail := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if s.Target != "" {
+		target, err := url.Parse(s.Target)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			fail("target", "%q is not an http(s) URL", s.Target)
+		}
+	}
+	if s.Timeout < 0 {
+		fail("timeout", "must be positive")
+	}
+	validateIgnore("ignore", s.Ignore, fail)
+	if len(s.Requests) == 0 {
+		fail("requests", "at least one is required")
+	}
+
+	names := map[string]bool{}
+	for i, req := range s.Requests {
+		path := fmt.Sprintf("requests[%d]", i)
+		if !suiteNamePattern.MatchString(req.Name) {
+			fail(path+".name", "%q must be letters, digits, '.', '_' or '-', to name its golden file", req.Name)
+		} else if names[strings.ToLower(req.Name)] {
+			fail(path+".name", "%q is used twice", req.Name)
+		}
+		names[strings.ToLower(req.Name)] = true
+		if !methodPattern.MatchString(req.Method) {
+			fail(path+".method", "%q is not an upper case HTTP method", req.Method)
+		}
+		if !strings.HasPrefix(req.Path, "/") {
+			fail(path+".path", "must start with /")
+		} else if _, err := url.ParseRequestURI(req.Path); err != nil {
+			fail(path+".path", "%v", err)
+		}
+		validateIgnore(path+".ignore", req.Ignore, fail)
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errs: errs}
+	}
+	return nil
+}
+
+func validateIgnore(path string, rules IgnoreRules, fail func(path, format string, args ...any)) {
+	for i, jsonPath := range rules.Paths {
+		if !ignorePathPattern.MatchString(jsonPath) {
+			fail(fmt.Sprintf("%s.paths[%d]", path, i), "%q is not a JSON path like $.items[*].id or $..updated_at", jsonPath)
+		}
+	}
+}
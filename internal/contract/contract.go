@@ -0,0 +1,145 @@
+package contract
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+const (
+	ScopeRoute    = "route"
+	ScopeRequest  = "request"
+	ScopeResponse = "response"
+)
+
+// Validator checks captured exchanges of one service against its OpenAPI
+// document.
+type Validator struct {
+	service string
+	router  routers.Router
+	stats   *Stats
+}
+
+// New loads the OpenAPI document at specPath. Its servers are replaced by
+// target so operations match the upstream URLs Golden Gate records.
+func New(service, specPath, target string, stats *Stats) (*Validator, error) {
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+
+	doc, err := loader.LoadFromFile(specPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	doc.Servers = openapi3.Servers{{URL: strings.TrimSuffix(target, "/")}}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Validator{
+		service: service,
+		router:  router,
+		stats:   stats,
+	}, nil
+}
+
+// Validate matches reqLog to an operation and validates its request and
+// response. The result is recorded in the per-endpoint stats.
+func (v *Validator) Validate(reqLog *types.RequestLog) (string, []types.ContractViolation) {
+	operation, violations := v.validate(reqLog)
+	v.stats.Record(v.service, operation, len(violations))
+	return operation, violations
+}
+
+func (v *Validator) validate(reqLog *types.RequestLog) (string, []types.ContractViolation) {
+	ctx := context.Background()
+
+	req, err := http.NewRequestWithContext(ctx, reqLog.Method, reqLog.URL, bytes.NewReader(reqLog.Body))
+	if err != nil {
+		return "", []types.ContractViolation{{Scope: ScopeRoute, Message: err.Error()}}
+	}
+	req.Header = http.Header(reqLog.Headers).Clone()
+
+	route, pathParams, err := v.router.FindRoute(req)
+	if err != nil {
+		return "", []types.ContractViolation{{
+			Scope:   ScopeRoute,
+			Message: fmt.Sprintf("no operation for %s %s: %v", req.Method, req.URL.Path, err),
+		}}
+	}
+	operation := req.Method + " " + route.Path
+
+	requestInput := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError:            true,
+			IncludeResponseStatus: true,
+			AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+		},
+	}
+
+	var violations []types.ContractViolation
+	if err := openapi3filter.ValidateRequest(ctx, requestInput); err != nil {
+		violations = append(violations, toViolations(ScopeRequest, err)...)
+	}
+
+	if reqLog.Response != nil {
+		responseInput := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: requestInput,
+			Status:                 reqLog.Response.StatusCode,
+			Header:                 http.Header(reqLog.Response.Headers),
+			Body:                   io.NopCloser(bytes.NewReader(decodedBody(reqLog.Response))),
+			Options:                requestInput.Options,
+		}
+		if err := openapi3filter.ValidateResponse(ctx, responseInput); err != nil {
+			violations = append(violations, toViolations(ScopeResponse, err)...)
+		}
+	}
+
+	return operation, violations
+}
+
+func toViolations(scope string, err error) []types.ContractViolation {
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		var violations []types.ContractViolation
+		for _, e := range multi {
+			violations = append(violations, toViolations(scope, e)...)
+		}
+		return violations
+	}
+	return []types.ContractViolation{{Scope: scope, Message: err.Error()}}
+}
+
+// decodedBody undoes gzip content encoding so the body can be parsed.
+func decodedBody(resp *types.ResponseLog) []byte {
+	if !strings.EqualFold(http.Header(resp.Headers).Get("Content-Encoding"), "gzip") {
+		return resp.Body
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(resp.Body))
+	if err != nil {
+		return resp.Body
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return resp.Body
+	}
+	return body
+}
@@ -0,0 +1,68 @@
+package contract
+
+import (
+	"sort"
+	"sync"
+)
+
+// EndpointStats counts validated exchanges for one operation.
+type EndpointStats struct {
+	Service   string `json:"service"`
+	Operation string `json:"operation"`
+	Total     int    `json:"total"`
+	Invalid   int    `json:"invalid"`
+}
+
+type endpointKey struct {
+	service   string
+	operation string
+}
+
+// Stats aggregates validation results per service and operation. It is
+// shared by the validators of every service and survives config reloads.
+type Stats struct {
+	mu        sync.Mutex
+	endpoints map[endpointKey]*EndpointStats
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		endpoints: map[endpointKey]*EndpointStats{},
+	}
+}
+
+// Record counts one exchange. Exchanges that matched no operation are
+// grouped under an empty operation.
+func (s *Stats) Record(service, operation string, violations int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := endpointKey{service: service, operation: operation}
+	endpoint, ok := s.endpoints[key]
+	if !ok {
+		endpoint = &EndpointStats{Service: service, Operation: operation}
+		s.endpoints[key] = endpoint
+	}
+	endpoint.Total++
+	if violations > 0 {
+		endpoint.Invalid++
+	}
+}
+
+// Endpoints returns a snapshot sorted by service and operation.
+func (s *Stats) Endpoints() []EndpointStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoints := make([]EndpointStats, 0, len(s.endpoints))
+	for _, endpoint := range s.endpoints {
+		endpoints = append(endpoints, *endpoint)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Service != endpoints[j].Service {
+			return endpoints[i].Service < endpoints[j].Service
+		}
+		return endpoints[i].Operation < endpoints[j].Operation
+	})
+	return endpoints
+}
@@ -0,0 +1,61 @@
+// Package assets embeds the dashboard stylesheet and script, so the
+// dashboard works without access to a CDN.
+package assets
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+//go:embed *.css *.js
+var files embed.FS
+
+// Version identifies the embedded files. It is derived from their content,
+// so asset URLs change whenever a file does and can be cached forever.
+var Version = hash()
+
+// URL returns the versioned path of the embedded file name under the
+// dashboard mounted at basePath.
+func URL(basePath, name string) string {
+	return basePath + "/static/" + Version + "/" + name
+}
+
+// Handler serves the embedded files at prefix/{version}/{name}. Files of the
+// current version are cached for a year; other versions still get the
+// current file, without caching, so pages rendered before an upgrade load.
+func Handler(prefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, name, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, prefix+"/"), "/")
+		if !ok || name == "" || strings.Contains(name, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		if _, err := fs.Stat(files, name); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		if version == Version {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		w.Header().Set("ETag", `"`+Version+`"`)
+		http.ServeFileFS(w, r, files, name)
+	})
+}
+
+func hash() string {
+	sum := sha256.New()
+	names, _ := fs.Glob(files, "*")
+	for _, name := range names {
+		data, _ := files.ReadFile(name)
+		sum.Write([]byte(name))
+		sum.Write(data)
+	}
+	return hex.EncodeToString(sum.Sum(nil))[:12]
+}
@@ -0,0 +1,176 @@
+// Dashboard behaviour. Pages carry no inline scripts or event handlers, so
+// the Content Security Policy can forbid them; elements opt in through data
+// attributes instead:
+//
+//   data-copy="id"         copies the text of element id
+//   data-toggle="id"       shows or hides element id
+//   data-snippets          a snippet selector, see Snippets in snippets.templ
+//   data-tab-button="name" shows the data-tab="name" panel
+//   data-live-toggle       pauses or resumes live updates
+"use strict";
+
+(() => {
+  const copy = (text) => navigator.clipboard.writeText(text);
+
+  const showSnippet = (root) => {
+    const lang = root.querySelector("[data-snippet-lang]").value;
+    const omit = String(root.querySelector("[data-snippet-omit]").checked);
+    root.querySelectorAll("[data-lang]").forEach((el) => {
+      el.classList.toggle("hidden", el.dataset.lang !== lang || el.dataset.omit !== omit);
+    });
+  };
+
+  const copySnippet = (root) => {
+    const visible = Array.from(root.querySelectorAll("[data-lang]")).find((el) => !el.classList.contains("hidden"));
+    copy(visible.textContent);
+  };
+
+  const showTab = (name) => {
+    document.querySelectorAll("[data-tab]").forEach((el) => {
+      el.classList.toggle("hidden", el.dataset.tab !== name);
+    });
+    document.querySelectorAll("[data-tab-button]").forEach((el) => {
+      const active = el.dataset.tabButton === name;
+      el.classList.toggle("border-blue-500", active);
+      el.classList.toggle("text-blue-600", active);
+      el.classList.toggle("border-transparent", !active);
+    });
+    history.replaceState(null, "", "#" + name);
+  };
+
+  let toggleLive = () => {};
+
+  document.addEventListener("click", (event) => {
+    const el = event.target.closest("[data-copy], [data-toggle], [data-snippet-copy], [data-tab-button], [data-live-toggle]");
+    if (!el) {
+      return;
+    }
+    if (el.dataset.copy) {
+      copy(document.getElementById(el.dataset.copy).textContent);
+    } else if (el.dataset.toggle) {
+      document.getElementById(el.dataset.toggle).classList.toggle("hidden");
+    } else if (el.hasAttribute("data-snippet-copy")) {
+      copySnippet(el.closest("[data-snippets]"));
+    } else if (el.dataset.tabButton) {
+      showTab(el.dataset.tabButton);
+    } else if (el.hasAttribute("data-live-toggle")) {
+      toggleLive();
+    }
+  });
+
+  document.addEventListener("change", (event) => {
+    const root = event.target.closest("[data-snippets]");
+    if (root) {
+      showSnippet(root);
+    }
+  });
+
+  // Live updates: new captures are prepended and pending ones replaced
+  // once the upstream answers. While paused, new captures are queued.
+  const startLive = (list) => {
+    const status = document.getElementById("live-status");
+    const toggle = document.getElementById("live-toggle");
+    if (!list.hasAttribute("data-live")) {
+      status.textContent = "Live updates only on the first page";
+      toggle.classList.add("hidden");
+      return;
+    }
+    const max = parseInt(list.dataset.max, 10);
+    const badge = document.getElementById("new-requests");
+    let paused = false;
+    let queued = [];
+
+    // Catch up from the oldest pending card, or the newest card, to
+    // cover what happened since the page was rendered.
+    const cards = Array.from(list.children);
+    const pending = cards.filter((el) => el.hasAttribute("data-pending"));
+    const after = pending.length > 0
+      ? Math.min(...pending.map((el) => Number(el.dataset.seq))) - 1
+      : Math.max(0, ...cards.map((el) => Number(el.dataset.seq)));
+    const url = new URL(list.dataset.events, window.location.href);
+    url.searchParams.set("after", after);
+    const source = new EventSource(url);
+
+    const parse = (html) => {
+      const template = document.createElement("template");
+      template.innerHTML = html.trim();
+      return template.content.firstElementChild;
+    };
+
+    const insert = (card) => {
+      card.classList.add("ring-2", "ring-blue-300");
+      setTimeout(() => card.classList.remove("ring-2", "ring-blue-300"), 3000);
+      list.prepend(card);
+      document.getElementById("no-requests")?.remove();
+      while (list.children.length > max) {
+        list.lastElementChild.remove();
+      }
+    };
+
+    const replace = (card) => {
+      const existing = document.getElementById(card.id);
+      if (existing) {
+        existing.replaceWith(card);
+        return true;
+      }
+      const i = queued.findIndex((el) => el.id === card.id);
+      if (i >= 0) {
+        queued[i] = card;
+        return true;
+      }
+      return false;
+    };
+
+    const updateBadge = () => {
+      badge.textContent = queued.length + " new request" + (queued.length === 1 ? "" : "s");
+      badge.classList.toggle("hidden", queued.length === 0);
+    };
+
+    // A completed request may only now match the filters, so updates
+    // for unknown cards are added like new ones.
+    const receive = (event) => {
+      const card = parse(event.data);
+      if (replace(card)) {
+        return;
+      }
+      if (paused) {
+        queued.push(card);
+        updateBadge();
+      } else {
+        insert(card);
+      }
+    };
+    source.addEventListener("add", receive);
+    source.addEventListener("update", receive);
+    source.addEventListener("remove", (event) => {
+      document.getElementById(event.data)?.remove();
+      queued = queued.filter((el) => el.id !== event.data);
+      updateBadge();
+    });
+    source.onopen = () => { status.textContent = paused ? "Paused" : "Live"; };
+    source.onerror = () => { status.textContent = "Reconnecting…"; };
+
+    toggleLive = () => {
+      paused = !paused;
+      toggle.textContent = paused ? "Resume" : "Pause";
+      status.textContent = paused ? "Paused" : "Live";
+      if (!paused) {
+        queued.forEach(insert);
+        queued = [];
+        updateBadge();
+      }
+    };
+  };
+
+  document.addEventListener("DOMContentLoaded", () => {
+    const list = document.querySelector("#requests[data-events]");
+    if (list) {
+      startLive(list);
+    }
+
+    if (document.querySelector("[data-tab]")) {
+      const name = window.location.hash.slice(1);
+      showTab(document.querySelector(`[data-tab="${CSS.escape(name)}"]`) ? name : "headers");
+    }
+  });
+})();
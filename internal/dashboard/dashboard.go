@@ -1,23 +1,333 @@
 package dashboard
 
 import (
+	"bytes"
+	"fmt"
 	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/mtavano/golden-gate/internal/alert"
+	"github.com/mtavano/golden-gate/internal/auth"
+	"github.com/mtavano/golden-gate/internal/config"
+	"github.com/mtavano/golden-gate/internal/contract"
+	"github.com/mtavano/golden-gate/internal/dashboard/assets"
 	"github.com/mtavano/golden-gate/internal/dashboard/views"
+	"github.com/mtavano/golden-gate/internal/filter"
+	"github.com/mtavano/golden-gate/internal/mirror"
+	"github.com/mtavano/golden-gate/internal/specgen"
+	"github.com/mtavano/golden-gate/internal/stats"
 	"github.com/mtavano/golden-gate/internal/types"
 )
 
+// ConfigStatus gives access to the active configuration and reports the
+// outcome of the last (re)load.
+type ConfigStatus interface {
+	Config() *config.Config
+	LastReload() (time.Time, error)
+}
+
 type Handler struct {
 	requestStore *types.RequestStore
+	mirrorStore  *mirror.Store
+	contracts    *contract.Stats
+	alerts       *alert.Manager
+	configStatus ConfigStatus
 }
 
-func NewHandler(requestStore *types.RequestStore) *Handler {
+func NewHandler(requestStore *types.RequestStore, mirrorStore *mirror.Store, contracts *contract.Stats, alerts *alert.Manager, configStatus ConfigStatus) *Handler {
 	return &Handler{
 		requestStore: requestStore,
+		mirrorStore:  mirrorStore,
+		contracts:    contracts,
+		alerts:       alerts,
+		configStatus: configStatus,
+	}
+}
+
+// contentSecurityPolicy only allows the embedded assets, so the dashboard
+// works offline and injected markup cannot run scripts or load anything.
+const contentSecurityPolicy = "default-src 'self'; script-src 'self'; style-src 'self'; img-src 'self' data:; connect-src 'self'; object-src 'none'; base-uri 'none'; frame-ancestors 'none'; form-action 'self'"
+
+// Register mounts the dashboard pages under basePath. Every page needs the
+// viewer role; the login pages and static assets are public.
+func (h *Handler) Register(r *mux.Router, basePath string, authn *auth.Authenticator) {
+	handle := func(path string, next http.Handler) *mux.Route {
+		return r.Handle(path, secure(next))
+	}
+	view := func(next http.HandlerFunc) http.Handler {
+		return authn.Require(auth.RoleViewer, next)
+	}
+
+	handle(basePath, view(h.index(basePath)))
+	handle(basePath+"/events", view(h.events(basePath)))
+	handle(basePath+"/requests/{id}", view(h.request(basePath)))
+	handle(basePath+"/stats", view(h.stats(basePath)))
+	handle(basePath+"/mirror", view(h.mirror(basePath)))
+	handle(basePath+"/contracts", view(h.contractStats(basePath)))
+	handle(basePath+"/alerts", view(h.alertRules(basePath)))
+	handle(basePath+"/openapi", view(h.specs(basePath)))
+	handle(basePath+"/openapi/{service}", view(h.downloadSpec))
+
+	handle(basePath+"/login", login(basePath, authn))
+	handle(basePath+"/logout", logout(basePath, authn)).Methods(http.MethodPost)
+	handle(basePath+"/oidc/login", oidcLogin(basePath, authn))
+	handle(basePath+"/oidc/callback", oidcCallback(basePath, authn))
+
+	r.PathPrefix(basePath+"/static/").Handler(secure(assets.Handler(basePath+"/static"))).Methods(http.MethodGet, http.MethodHead)
+}
+
+// secure sets the Content Security Policy and related headers on dashboard
+// responses.
+func secure(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Referrer-Policy", "same-origin")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) index(basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var list views.RequestList
+		f, err := filter.Parse(r.URL.Query())
+		if err != nil {
+			list.FilterErr = err.Error()
+			f, _ = filter.Parse(nil)
+		}
+		list.Filter = f
+
+		// Filter what the caller can see, so redacted values cannot be
+		// probed with header or body filters
+		requests := visibleAll(r, h.requestStore.GetRequests())
+		// Newest first, so live updates can be prepended
+		slices.Reverse(requests)
+		list.Requests, list.Total = f.Apply(requests)
+
+		var configErr, traceURL string
+		if h.configStatus != nil {
+			if at, err := h.configStatus.LastReload(); err != nil {
+				configErr = at.Format("2006-01-02 15:04:05") + ": " + err.Error()
+			}
+			traceURL = h.configStatus.Config().Tracing.TraceURL
+		}
+		list.Services = h.services()
+
+		views.Dashboard(basePath, list, configErr, traceURL, h.alerts.Firing()).Render(r.Context(), w)
+	}
+}
+
+// stats reports on the captures matching the dashboard filter, ignoring
+// pagination.
+func (h *Handler) stats(basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var list views.RequestList
+		f, err := filter.Parse(r.URL.Query())
+		if err != nil {
+			list.FilterErr = err.Error()
+			f, _ = filter.Parse(nil)
+		}
+		list.Filter = f
+		list.Services = h.services()
+
+		for _, req := range visibleAll(r, h.requestStore.GetRequests()) {
+			if f.Match(req) {
+				list.Requests = append(list.Requests, req)
+			}
+		}
+		list.Total = len(list.Requests)
+
+		views.Stats(basePath, list, stats.Compute(list.Requests)).Render(r.Context(), w)
+	}
+}
+
+// events streams captures as server-sent events. Each event carries a
+// rendered request card: "add" for new captures and "update" when a pending
+// one completes. The dashboard filter parameters apply; a completed request
+// that no longer matches them is sent as "remove" with the card id. Captures
+// after the ?after query or Last-Event-ID sequence number are replayed first.
+func (h *Handler) events(basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.streamEvents(w, r, basePath)
+	}
+}
+
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request, basePath string) {
+	f, err := filter.Parse(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Subscribe before taking the snapshot so nothing falls in between
+	events, unsubscribe := h.requestStore.Subscribe(256)
+	defer unsubscribe()
+
+	after, _ := strconv.ParseUint(r.URL.Query().Get("after"), 10, 64)
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		after, _ = strconv.ParseUint(lastID, 10, 64)
+	}
+
+	var traceURL string
+	if h.configStatus != nil {
+		traceURL = h.configStatus.Config().Tracing.TraceURL
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(w)
+	for _, req := range visibleAll(r, h.requestStore.GetRequests()) {
+		if req.Seq > after && f.Match(req) {
+			writeEvent(w, r, basePath, types.EventAdd, req, traceURL)
+		}
+	}
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case event := <-events:
+			req := visible(r, event.Request)
+			switch {
+			case f.Match(req):
+				writeEvent(w, r, basePath, event.Type, req, traceURL)
+			case event.Type == types.EventUpdate:
+				fmt.Fprintf(w, "event: remove\ndata: request-%d\n\n", event.Request.Seq)
+			default:
+				continue
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, r *http.Request, basePath, eventType string, req *types.RequestLog, traceURL string) {
+	var card bytes.Buffer
+	if err := views.RequestCard(basePath, req, traceURL).Render(r.Context(), &card); err != nil {
+		return
+	}
+
+	fmt.Fprintf(w, "id: %d\nevent: %s\n", req.Seq, eventType)
+	for _, line := range strings.Split(card.String(), "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// request shows a single capture by id.
+func (h *Handler) request(basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := h.requestStore.GetRequest(mux.Vars(r)["id"])
+		if req == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		var traceURL string
+		if h.configStatus != nil {
+			traceURL = h.configStatus.Config().Tracing.TraceURL
+		}
+
+		views.Request(basePath, visible(r, req), traceURL).Render(r.Context(), w)
+	}
+}
+
+// services returns the configured service names, sorted.
+func (h *Handler) services() []string {
+	if h.configStatus == nil {
+		return nil
 	}
+
+	var services []string
+	for name := range h.configStatus.Config().Services {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	return services
 }
 
-func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	requests := h.requestStore.GetRequests()
-	views.Dashboard(requests).Render(r.Context(), w)
-} 
\ No newline at end of file
+func (h *Handler) mirror(basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var mismatches []*mirror.Result
+		results := visibleResults(r, h.mirrorStore.Results())
+		for i := len(results) - 1; i >= 0; i-- {
+			if results[i].Mismatch() {
+				mismatches = append(mismatches, results[i])
+			}
+		}
+
+		views.Mirror(basePath, len(results), mismatches).Render(r.Context(), w)
+	}
+}
+
+func (h *Handler) contractStats(basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		views.Contracts(basePath, h.contracts.Endpoints()).Render(r.Context(), w)
+	}
+}
+
+func (h *Handler) alertRules(basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		views.Alerts(basePath, h.alerts.Rules(), h.alerts.History()).Render(r.Context(), w)
+	}
+}
+
+func (h *Handler) specs(basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		services := make([]string, 0)
+		for name := range h.configStatus.Config().Services {
+			services = append(services, name)
+		}
+		sort.Strings(services)
+
+		views.Specs(basePath, services).Render(r.Context(), w)
+	}
+}
+
+func (h *Handler) downloadSpec(w http.ResponseWriter, r *http.Request) {
+	service := mux.Vars(r)["service"]
+	svc, ok := h.configStatus.Config().Services[service]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	doc, err := specgen.Generate(service, svc.Target, visibleAll(r, h.requestStore.GetRequests()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	marshal, ext, contentType := specgen.MarshalJSON, "json", "application/json"
+	if r.URL.Query().Get("format") == "yaml" {
+		marshal, ext, contentType = specgen.MarshalYAML, "yaml", "application/yaml"
+	}
+	data, err := marshal(doc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.openapi.%s"`, service, ext))
+	w.Write(data)
+}
@@ -0,0 +1,90 @@
+package dashboard
+
+import (
+	"net/http"
+
+	"github.com/mtavano/golden-gate/internal/auth"
+	"github.com/mtavano/golden-gate/internal/dashboard/views"
+	"go.uber.org/zap"
+)
+
+// login shows the sign in page and checks submitted passwords.
+func login(basePath string, authn *auth.Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page := views.LoginPage{
+			Next:      auth.SafeNext(r.FormValue("next"), basePath),
+			Passwords: authn.PasswordsEnabled(),
+			OIDC:      authn.OIDCEnabled(),
+		}
+		if authn.Open() {
+			http.Redirect(w, r, page.Next, http.StatusSeeOther)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			username := r.PostFormValue("username")
+			p, err := authn.Login(username, r.PostFormValue("password"))
+			if err == nil {
+				err = authn.StartSession(w, r, p)
+			}
+			if err != nil {
+				zap.L().Warn("dashboard login failed", zap.String("username", username), zap.String("remote", r.RemoteAddr))
+				page.Username = username
+				page.Error = err.Error()
+				w.WriteHeader(http.StatusUnauthorized)
+				views.Login(basePath, page).Render(r.Context(), w)
+				return
+			}
+
+			zap.L().Info("dashboard login", zap.String("username", p.Name), zap.String("role", p.Role.String()))
+			http.Redirect(w, r, page.Next, http.StatusSeeOther)
+			return
+		}
+
+		views.Login(basePath, page).Render(r.Context(), w)
+	}
+}
+
+func logout(basePath string, authn *auth.Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authn.EndSession(w, r)
+		http.Redirect(w, r, basePath+"/login", http.StatusSeeOther)
+	}
+}
+
+func oidcLogin(basePath string, authn *auth.Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next := auth.SafeNext(r.URL.Query().Get("next"), basePath)
+		if err := authn.OIDCLogin(w, r, next); err != nil {
+			zap.L().Error("OIDC login failed", zap.Error(err))
+			renderLoginError(w, r, basePath, authn, next, err)
+		}
+	}
+}
+
+func oidcCallback(basePath string, authn *auth.Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, next, err := authn.OIDCCallback(w, r)
+		if err == nil {
+			err = authn.StartSession(w, r, p)
+		}
+		if err != nil {
+			zap.L().Warn("OIDC login failed", zap.Error(err))
+			renderLoginError(w, r, basePath, authn, basePath, err)
+			return
+		}
+
+		zap.L().Info("dashboard login", zap.String("username", p.Name), zap.String("role", p.Role.String()), zap.String("method", p.Method))
+		http.Redirect(w, r, auth.SafeNext(next, basePath), http.StatusSeeOther)
+	}
+}
+
+func renderLoginError(w http.ResponseWriter, r *http.Request, basePath string, authn *auth.Authenticator, next string, err error) {
+	w.WriteHeader(http.StatusUnauthorized)
+	views.Login(basePath, views.LoginPage{
+		Next:      next,
+		Passwords: authn.PasswordsEnabled(),
+		OIDC:      authn.OIDCEnabled(),
+		Error:     err.Error(),
+	}).Render(r.Context(), w)
+}
@@ -0,0 +1,213 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mtavano/golden-gate/internal/auth"
+	"github.com/mtavano/golden-gate/internal/mirror"
+	"github.com/mtavano/golden-gate/internal/snippet"
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+const redacted = "[redacted]"
+
+// canSeeSecrets reports whether the caller of r sees captures unredacted.
+func canSeeSecrets(r *http.Request) bool {
+	p := auth.FromContext(r.Context())
+	return p != nil && p.Role.Allows(auth.RoleOperator)
+}
+
+// visible returns req as the caller of r may see it. For viewers, the values
+// of credential headers, query parameters and JSON body fields are masked,
+// using the same names as the snippet "Omit secret headers" option. Form
+// bodies are masked like query strings.
+func visible(r *http.Request, req *types.RequestLog) *types.RequestLog {
+	if canSeeSecrets(r) {
+		return req
+	}
+
+	clone := *req
+	clone.Headers = redactValues(req.Headers)
+	clone.Query = redactValues(req.Query)
+	clone.URL = redactURL(req.URL)
+	clone.IncomingURL = redactURL(req.IncomingURL)
+	clone.Body = redactBody(req.Headers, req.Body)
+	clone.Error = redactURLs(req.Error)
+	if req.Response != nil {
+		response := *req.Response
+		response.Headers = redactValues(req.Response.Headers)
+		response.Body = redactBody(req.Response.Headers, req.Response.Body)
+		clone.Response = &response
+	}
+	return &clone
+}
+
+func visibleAll(r *http.Request, requests []*types.RequestLog) []*types.RequestLog {
+	if canSeeSecrets(r) {
+		return requests
+	}
+
+	out := make([]*types.RequestLog, len(requests))
+	for i, req := range requests {
+		out[i] = visible(r, req)
+	}
+	return out
+}
+
+// visibleResults masks mirror differences in credential headers and fields.
+func visibleResults(r *http.Request, results []*mirror.Result) []*mirror.Result {
+	if canSeeSecrets(r) {
+		return results
+	}
+
+	out := make([]*mirror.Result, len(results))
+	for i, result := range results {
+		clone := *result
+		clone.Request = visible(r, result.Request)
+		clone.MirrorURL = redactURL(result.MirrorURL)
+		clone.Error = redactURLs(result.Error)
+		if result.Response != nil {
+			response := *result.Response
+			response.Headers = redactValues(result.Response.Headers)
+			response.Body = redactBody(result.Response.Headers, result.Response.Body)
+			clone.Response = &response
+		}
+		clone.Diffs = make([]mirror.Difference, len(result.Diffs))
+		for j, diff := range result.Diffs {
+			if isSecret(diff.Path) {
+				diff.Primary, diff.Mirror = redacted, redacted
+			}
+			clone.Diffs[j] = diff
+		}
+		out[i] = &clone
+	}
+	return out
+}
+
+// isSecret reports whether a header, query parameter or field name usually
+// carries credentials. Query and JSON names often use underscores, like
+// api_key.
+func isSecret(name string) bool {
+	return snippet.IsSecretHeader(strings.ReplaceAll(name, "_", "-"))
+}
+
+func redactValues(values map[string][]string) map[string][]string {
+	if len(values) == 0 {
+		return values
+	}
+
+	out := make(map[string][]string, len(values))
+	for name, v := range values {
+		if isSecret(name) {
+			masked := make([]string, len(v))
+			for i := range masked {
+				masked[i] = redacted
+			}
+			v = masked
+		}
+		out[name] = v
+	}
+	return out
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+
+	query := u.Query()
+	changed := false
+	for name := range query {
+		if isSecret(name) {
+			query.Set(name, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return raw
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// urlPattern matches the URLs that errors quote, such as those of net/http.
+var urlPattern = regexp.MustCompile(`https?://[^\s"]+`)
+
+// redactURLs masks secret query parameters of the URLs in an error message.
+func redactURLs(text string) string {
+	return urlPattern.ReplaceAllStringFunc(text, redactURL)
+}
+
+// redactBody masks secret fields of a JSON or form-encoded body. Other
+// bodies are returned unchanged.
+func redactBody(header http.Header, body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	if mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type")); mediaType == "application/x-www-form-urlencoded" {
+		return redactForm(body)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return body
+	}
+	if !redactJSON(doc) {
+		return body
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+func redactForm(body []byte) []byte {
+	// ParseQuery keeps the pairs it could decode even when one is malformed,
+	// and those may still hold secrets
+	form, _ := url.ParseQuery(string(body))
+
+	changed := false
+	for name := range form {
+		if isSecret(name) {
+			form.Set(name, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return body
+	}
+	return []byte(form.Encode())
+}
+
+func redactJSON(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if isSecret(key) {
+				v[key] = redacted
+				changed = true
+			} else if redactJSON(value) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, value := range v {
+			if redactJSON(value) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,144 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/mtavano/golden-gate/internal/auth"
+	"github.com/mtavano/golden-gate/internal/mirror"
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+func requestAs(role auth.Role) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	return r.WithContext(auth.NewContext(r.Context(), &auth.Principal{Name: "ana", Role: role}))
+}
+
+func TestVisible(t *testing.T) {
+	capture := func() *types.RequestLog {
+		return &types.RequestLog{
+			URL:         "https://api.example.com/orders?api_key=k3y&page=2",
+			IncomingURL: "/shop/orders?api_key=k3y&page=2",
+			Query:       map[string][]string{"api_key": {"k3y"}, "page": {"2"}},
+			Headers: http.Header{
+				"Authorization": {"Bearer s3cret"},
+				"X-Api-Key":     {"k3y"},
+				"Accept":        {"application/json"},
+			},
+			Body: []byte(`{"user":"ana","password":"hunter2","card":{"number":"4242","cvc_token":"t0k"}}`),
+			Response: &types.ResponseLog{
+				StatusCode: 200,
+				Headers:    http.Header{"Set-Cookie": {"session=abc"}, "Content-Type": {"application/json"}},
+				Body:       []byte(`{"access_token":"at","items":[{"refresh_token":"rt","id":1}]}`),
+			},
+		}
+	}
+
+	t.Run("operator sees everything", func(t *testing.T) {
+		req := capture()
+		if got := visible(requestAs(auth.RoleOperator), req); got != req {
+			t.Errorf("visible returned a copy for an operator")
+		}
+	})
+
+	t.Run("viewer", func(t *testing.T) {
+		req := capture()
+		got := visible(requestAs(auth.RoleViewer), req)
+
+		checks := []struct {
+			name      string
+			got, want any
+		}{
+			{"url", got.URL, "https://api.example.com/orders?api_key=%5Bredacted%5D&page=2"},
+			{"incoming url", got.IncomingURL, "/shop/orders?api_key=%5Bredacted%5D&page=2"},
+			{"query", got.Query["api_key"], []string{redacted}},
+			{"other query", got.Query["page"], []string{"2"}},
+			{"authorization", got.Headers["Authorization"], []string{redacted}},
+			{"api key header", got.Headers["X-Api-Key"], []string{redacted}},
+			{"other header", got.Headers["Accept"], []string{"application/json"}},
+			{"body", string(got.Body), `{"card":{"cvc_token":"[redacted]","number":"4242"},"password":"[redacted]","user":"ana"}`},
+			{"response cookie", got.Response.Headers["Set-Cookie"], []string{redacted}},
+			{"response body", string(got.Response.Body), `{"access_token":"[redacted]","items":[{"id":1,"refresh_token":"[redacted]"}]}`},
+		}
+		for _, c := range checks {
+			if !reflect.DeepEqual(c.got, c.want) {
+				t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+			}
+		}
+
+		if !reflect.DeepEqual(req, capture()) {
+			t.Errorf("visible modified the stored capture")
+		}
+	})
+
+	t.Run("no principal", func(t *testing.T) {
+		got := visible(httptest.NewRequest(http.MethodGet, "/dashboard", nil), capture())
+		if want := []string{redacted}; !reflect.DeepEqual(got.Headers["Authorization"], want) {
+			t.Errorf("Authorization = %v without a principal, want it redacted", got.Headers["Authorization"])
+		}
+	})
+}
+
+func TestRedactBody(t *testing.T) {
+	form := http.Header{"Content-Type": {"application/x-www-form-urlencoded; charset=utf-8"}}
+	json := http.Header{"Content-Type": {"application/json"}}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   string
+		want   string
+	}{
+		{"json", json, `{"token":"t","n":1.50}`, `{"n":1.50,"token":"[redacted]"}`},
+		{"json without secrets is untouched", json, `{"b": 1, "a": 2}`, `{"b": 1, "a": 2}`},
+		{"form", form, "grant_type=refresh_token&refresh_token=rt&client_id=gg&client_secret=cs",
+			"client_id=gg&client_secret=%5Bredacted%5D&grant_type=refresh_token&refresh_token=%5Bredacted%5D"},
+		{"form password", form, "username=ana&password=hunter2", "password=%5Bredacted%5D&username=ana"},
+		{"malformed form", form, "password=hunter2&bad=%zz", "password=%5Bredacted%5D"},
+		{"form without secrets is untouched", form, "b=1&a=2", "b=1&a=2"},
+		{"form body sent as text", http.Header{"Content-Type": {"text/plain"}}, "password=hunter2", "password=hunter2"},
+		{"empty", form, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(redactBody(tt.header, []byte(tt.body))); got != tt.want {
+				t.Errorf("redactBody(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisibleResults(t *testing.T) {
+	result := &mirror.Result{
+		Request:   &types.RequestLog{URL: "https://api.example.com/orders?api_key=k3y"},
+		MirrorURL: "https://mirror.example.com/orders?api_key=k3y&page=2",
+		Error:     `Get "https://mirror.example.com/orders?api_key=k3y": context deadline exceeded`,
+		Diffs:     []mirror.Difference{{Path: "body.token", Primary: "a", Mirror: "b"}, {Path: "body.id", Primary: "1", Mirror: "2"}},
+	}
+
+	if got := visibleResults(requestAs(auth.RoleOperator), []*mirror.Result{result}); got[0] != result {
+		t.Errorf("visibleResults returned a copy for an operator")
+	}
+
+	got := visibleResults(requestAs(auth.RoleViewer), []*mirror.Result{result})[0]
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"request url", got.Request.URL, "https://api.example.com/orders?api_key=%5Bredacted%5D"},
+		{"mirror url", got.MirrorURL, "https://mirror.example.com/orders?api_key=%5Bredacted%5D&page=2"},
+		{"error", got.Error, `Get "https://mirror.example.com/orders?api_key=%5Bredacted%5D": context deadline exceeded`},
+		{"secret diff", got.Diffs[0], mirror.Difference{Path: "body.token", Primary: redacted, Mirror: redacted}},
+		{"other diff", got.Diffs[1], result.Diffs[1]},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if result.MirrorURL != "https://mirror.example.com/orders?api_key=k3y&page=2" || result.Diffs[0].Primary != "a" {
+		t.Errorf("visibleResults modified the stored result")
+	}
+}
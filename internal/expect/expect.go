@@ -0,0 +1,254 @@
+// Package expect checks the captured traffic against expectations
+// registered by integration tests, like "exactly one POST /buda/orders whose
+// body contains {"type": "Bid"}".
+package expect
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mtavano/golden-gate/internal/filter"
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+// maxNearest is the number of non-matching requests reported per failed
+// expectation.
+const maxNearest = 3
+
+// Expectation describes requests a test expects to go through the proxy.
+// Empty fields match anything. Without Times, MinTimes or MaxTimes at least
+// one matching request is expected.
+type Expectation struct {
+	Name    string `json:"name,omitempty"`
+	Service string `json:"service,omitempty"`
+	Method  string `json:"method,omitempty"`
+	// Path is the incoming path. A {name} segment matches any one segment.
+	Path string `json:"path,omitempty"`
+	// Query and Headers values must equal one of the request's values.
+	Query   map[string]string `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is a JSON value contained in the request body: objects match
+	// when every listed key matches, arrays element by element, and other
+	// values when they are equal.
+	Body json.RawMessage `json:"body,omitempty"`
+	// Status is like "201", "2xx" or "200-299".
+	Status       string          `json:"status,omitempty"`
+	ResponseBody json.RawMessage `json:"response_body,omitempty"`
+	Times        *int            `json:"times,omitempty"`
+	MinTimes     *int            `json:"min_times,omitempty"`
+	MaxTimes     *int            `json:"max_times,omitempty"`
+}
+
+// matcher is a compiled Expectation.
+type matcher struct {
+	Expectation
+	segments             []string
+	body, responseBody   any
+	statusMin, statusMax int
+	min, max             int // max is -1 when unbounded
+}
+
+func compile(e Expectation) (*matcher, error) {
+	m := &matcher{Expectation: e, min: 1, max: -1}
+	m.Method = strings.ToUpper(e.Method)
+
+	if e.Path != "" {
+		if !strings.HasPrefix(e.Path, "/") {
+			return nil, fmt.Errorf("path: must start with /")
+		}
+		m.segments = strings.Split(e.Path, "/")
+	}
+	if e.Status != "" {
+		var err error
+		if m.statusMin, m.statusMax, err = filter.ParseStatus(e.Status); err != nil {
+			return nil, fmt.Errorf("status: %w", err)
+		}
+	}
+	if len(e.Body) > 0 {
+		if err := json.Unmarshal(e.Body, &m.body); err != nil {
+			return nil, fmt.Errorf("body: %w", err)
+		}
+	}
+	if len(e.ResponseBody) > 0 {
+		if err := json.Unmarshal(e.ResponseBody, &m.responseBody); err != nil {
+			return nil, fmt.Errorf("response_body: %w", err)
+		}
+	}
+
+	switch {
+	case e.Times != nil && (e.MinTimes != nil || e.MaxTimes != nil):
+		return nil, fmt.Errorf("times: cannot be combined with min_times or max_times")
+	case e.Times != nil:
+		m.min, m.max = *e.Times, *e.Times
+	default:
+		if e.MaxTimes != nil {
+			m.min, m.max = 0, *e.MaxTimes
+		}
+		if e.MinTimes != nil {
+			m.min = *e.MinTimes
+		}
+	}
+	if m.min < 0 || (e.MaxTimes != nil && m.max < 0) {
+		return nil, fmt.Errorf("times: must be positive")
+	}
+	if m.max >= 0 && m.min > m.max {
+		return nil, fmt.Errorf("min_times: greater than max_times")
+	}
+	return m, nil
+}
+
+// mismatches lists how req differs from the expectation; it matches when the
+// list is empty.
+func (m *matcher) mismatches(req *types.RequestLog) []string {
+	var diffs []string
+	if m.Service != "" && req.Service != m.Service {
+		diffs = append(diffs, fmt.Sprintf("service: got %q", req.Service))
+	}
+	if m.Method != "" && req.Method != m.Method {
+		diffs = append(diffs, "method: got "+req.Method)
+	}
+
+	incoming, _ := url.Parse(req.IncomingURL)
+	if incoming == nil {
+		incoming = &url.URL{}
+	}
+	if m.segments != nil && !m.matchPath(incoming.Path) {
+		diffs = append(diffs, "path: got "+incoming.Path)
+	}
+
+	query := incoming.Query()
+	for _, name := range sortedKeys(m.Query) {
+		if values, ok := query[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("query.%s: missing", name))
+		} else if !slices.Contains(values, m.Query[name]) {
+			diffs = append(diffs, fmt.Sprintf("query.%s: expected %q, got %q", name, m.Query[name], strings.Join(values, ", ")))
+		}
+	}
+	headers := http.Header(req.Headers)
+	for _, name := range sortedKeys(m.Headers) {
+		if values := headers.Values(name); len(values) == 0 {
+			diffs = append(diffs, fmt.Sprintf("header %s: missing", name))
+		} else if !slices.Contains(values, m.Headers[name]) {
+			diffs = append(diffs, fmt.Sprintf("header %s: expected %q, got %q", name, m.Headers[name], strings.Join(values, ", ")))
+		}
+	}
+	if m.body != nil {
+		diffs = append(diffs, matchBody("body", m.body, req.Body)...)
+	}
+
+	if m.Status != "" {
+		if req.Response == nil {
+			diffs = append(diffs, "status: no response")
+		} else if code := req.Response.StatusCode; code < m.statusMin || code > m.statusMax {
+			diffs = append(diffs, fmt.Sprintf("status: expected %s, got %d", m.Status, code))
+		}
+	}
+	if m.responseBody != nil {
+		if req.Response == nil {
+			diffs = append(diffs, "response_body: no response")
+		} else {
+			diffs = append(diffs, matchBody("response_body", m.responseBody, req.Response.Body)...)
+		}
+	}
+	return diffs
+}
+
+func (m *matcher) matchPath(path string) bool {
+	segments := strings.Split(path, "/")
+	if len(segments) != len(m.segments) {
+		return false
+	}
+	for i, want := range m.segments {
+		if strings.HasPrefix(want, "{") && strings.HasSuffix(want, "}") {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segments[i] != want {
+			return false
+		}
+	}
+	return true
+}
+
+// describe words the expected number of matches, like "exactly 1".
+func (m *matcher) describe() string {
+	switch {
+	case m.max < 0:
+		return fmt.Sprintf("at least %d", m.min)
+	case m.min == m.max:
+		return fmt.Sprintf("exactly %d", m.min)
+	case m.min == 0:
+		return fmt.Sprintf("at most %d", m.max)
+	default:
+		return fmt.Sprintf("between %d and %d", m.min, m.max)
+	}
+}
+
+func matchBody(path string, expected any, body []byte) []string {
+	var actual any
+	if err := json.Unmarshal(body, &actual); err != nil {
+		return []string{path + ": not JSON"}
+	}
+	return subset(path, expected, actual)
+}
+
+// subset reports where actual does not contain expected.
+func subset(path string, expected, actual any) []string {
+	switch want := expected.(type) {
+	case map[string]any:
+		got, ok := actual.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, got %s", path, encode(actual))}
+		}
+		var diffs []string
+		for _, key := range sortedKeys(want) {
+			value, ok := got[key]
+			if !ok {
+				diffs = append(diffs, path+"."+key+": missing")
+				continue
+			}
+			diffs = append(diffs, subset(path+"."+key, want[key], value)...)
+		}
+		return diffs
+	case []any:
+		got, ok := actual.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array, got %s", path, encode(actual))}
+		}
+		if len(got) != len(want) {
+			return []string{fmt.Sprintf("%s: expected %d elements, got %d", path, len(want), len(got))}
+		}
+		var diffs []string
+		for i := range want {
+			diffs = append(diffs, subset(path+"["+strconv.Itoa(i)+"]", want[i], got[i])...)
+		}
+		return diffs
+	default:
+		if encode(expected) != encode(actual) {
+			return []string{fmt.Sprintf("%s: expected %s, got %s", path, encode(expected), encode(actual))}
+		}
+		return nil
+	}
+}
+
+func encode(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,199 @@
+package expect
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+func TestMismatches(t *testing.T) {
+	req := &types.RequestLog{
+		Service:     "buda",
+		Method:      "POST",
+		IncomingURL: "/buda/orders/42?market=btc-clp&side=buy&side=sell",
+		Headers:     http.Header{"Content-Type": {"application/json"}, "X-Tenant": {"a", "b"}},
+		Body:        []byte(`{"type":"Bid","amount":{"value":"1.5","currency":"BTC"},"tags":["x","y"],"n":2}`),
+		Response:    &types.ResponseLog{StatusCode: 201, Body: []byte(`{"id":42,"state":"pending"}`)},
+	}
+
+	tests := []struct {
+		name        string
+		expectation Expectation
+		want        []string
+	}{
+		{"empty matches everything", Expectation{}, nil},
+		{"service and method", Expectation{Service: "buda", Method: "post"}, nil},
+		{"other service and method", Expectation{Service: "other", Method: "GET"}, []string{`service: got "buda"`, "method: got POST"}},
+		{"path", Expectation{Path: "/buda/orders/42"}, nil},
+		{"path parameter", Expectation{Path: "/buda/orders/{id}"}, nil},
+		{"path parameter is one segment", Expectation{Path: "/buda/{id}"}, []string{"path: got /buda/orders/42"}},
+		{"other path", Expectation{Path: "/buda/orders/43"}, []string{"path: got /buda/orders/42"}},
+		{"query", Expectation{Query: map[string]string{"market": "btc-clp", "side": "sell"}}, nil},
+		{"other query", Expectation{Query: map[string]string{"market": "eth-clp", "page": "1"}},
+			[]string{`query.market: expected "eth-clp", got "btc-clp"`, "query.page: missing"}},
+		{"headers", Expectation{Headers: map[string]string{"content-type": "application/json", "X-Tenant": "b"}}, nil},
+		{"other headers", Expectation{Headers: map[string]string{"X-Tenant": "c", "Authorization": "Bearer t"}},
+			[]string{"header Authorization: missing", `header X-Tenant: expected "c", got "a, b"`}},
+		{"body subset", Expectation{Body: json.RawMessage(`{"type":"Bid","amount":{"currency":"BTC"}}`)}, nil},
+		{"body array", Expectation{Body: json.RawMessage(`{"tags":["x","y"]}`)}, nil},
+		{"body number", Expectation{Body: json.RawMessage(`{"n":2.0}`)}, nil},
+		{"body differences", Expectation{Body: json.RawMessage(`{"type":"Ask","amount":{"value":1.5},"tags":["x"],"price":1}`)}, []string{
+			`body.amount.value: expected 1.5, got "1.5"`,
+			"body.price: missing",
+			"body.tags: expected 1 elements, got 2",
+			`body.type: expected "Ask", got "Bid"`,
+		}},
+		{"body shape", Expectation{Body: json.RawMessage(`{"amount":[1],"tags":{"a":1}}`)}, []string{
+			`body.amount: expected an array, got {"currency":"BTC","value":"1.5"}`,
+			`body.tags: expected an object, got ["x","y"]`,
+		}},
+		{"status", Expectation{Status: "2xx"}, nil},
+		{"status range", Expectation{Status: "200-299"}, nil},
+		{"other status", Expectation{Status: "200"}, []string{"status: expected 200, got 201"}},
+		{"response body", Expectation{ResponseBody: json.RawMessage(`{"state":"pending"}`)}, nil},
+		{"other response body", Expectation{ResponseBody: json.RawMessage(`{"state":"done"}`)}, []string{`response_body.state: expected "done", got "pending"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := compile(tt.expectation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.mismatches(req); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mismatches = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMismatchesWithoutResponse(t *testing.T) {
+	req := &types.RequestLog{Method: "GET", IncomingURL: "/a", Body: []byte("not json")}
+
+	tests := []struct {
+		name        string
+		expectation Expectation
+		want        []string
+	}{
+		{"status", Expectation{Status: "2xx"}, []string{"status: no response"}},
+		{"response body", Expectation{ResponseBody: json.RawMessage(`{}`)}, []string{"response_body: no response"}},
+		{"body not JSON", Expectation{Body: json.RawMessage(`{}`)}, []string{"body: not JSON"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := compile(tt.expectation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.mismatches(req); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mismatches = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	n := func(v int) *int { return &v }
+
+	tests := []struct {
+		name        string
+		expectation Expectation
+		want        string
+		wantErr     string
+	}{
+		{"default", Expectation{}, "at least 1", ""},
+		{"times", Expectation{Times: n(2)}, "exactly 2", ""},
+		{"never", Expectation{Times: n(0)}, "exactly 0", ""},
+		{"min times", Expectation{MinTimes: n(3)}, "at least 3", ""},
+		{"max times", Expectation{MaxTimes: n(2)}, "at most 2", ""},
+		{"min and max times", Expectation{MinTimes: n(1), MaxTimes: n(2)}, "between 1 and 2", ""},
+		{"times and min times", Expectation{Times: n(1), MinTimes: n(1)}, "", "cannot be combined"},
+		{"negative", Expectation{MinTimes: n(-1)}, "", "must be positive"},
+		{"negative max", Expectation{MaxTimes: n(-1)}, "", "must be positive"},
+		{"min above max", Expectation{MinTimes: n(3), MaxTimes: n(2)}, "", "greater than max_times"},
+		{"relative path", Expectation{Path: "orders"}, "", "path: must start with /"},
+		{"bad status", Expectation{Status: "2yy"}, "", "status:"},
+		{"bad body", Expectation{Body: json.RawMessage(`{`)}, "", "body:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := compile(tt.expectation)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("compile = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("compile = %v", err)
+			}
+			if got := m.describe(); got != tt.want {
+				t.Errorf("describe = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	capture := func(seq uint64, method, path string, status int) *types.RequestLog {
+		req := &types.RequestLog{ID: path, Seq: seq, Method: method, IncomingURL: path}
+		if status != 0 {
+			req.Response = &types.ResponseLog{StatusCode: status}
+		} else {
+			req.Pending = true
+		}
+		return req
+	}
+	requests := []*types.RequestLog{
+		capture(3, "POST", "/orders", 201),
+		capture(4, "GET", "/orders/1", 200),
+		capture(5, "GET", "/orders/2", 200),
+		capture(6, "GET", "/orders/3", 0),
+	}
+
+	once := 1
+	registry := NewRegistry()
+	set, err := registry.Add("checkout", []Expectation{
+		{Method: "POST", Path: "/orders", Status: "2xx"},
+		{Method: "GET", Path: "/orders/{id}", MaxTimes: &once},
+		{Method: "DELETE", Path: "/orders/{id}"},
+	}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := set.Verify(requests)
+	if report.Passed || report.Checked != 3 || report.Pending != 1 {
+		t.Errorf("report passed %v, checked %d, pending %d", report.Passed, report.Checked, report.Pending)
+	}
+	want := []struct {
+		passed  bool
+		message string
+		nearest int
+	}{
+		{true, "1 request matched, expected at least 1", 0},
+		{false, "2 requests matched, expected at most 1", 0},
+		{false, "0 requests matched, expected at least 1", 3},
+	}
+	for i, w := range want {
+		got := report.Results[i]
+		if got.Passed != w.passed || got.Message != w.message || len(got.Nearest) != w.nearest {
+			t.Errorf("results[%d] = passed %v, %q, %d nearest; want %v, %q, %d", i, got.Passed, got.Message, len(got.Nearest), w.passed, w.message, w.nearest)
+		}
+	}
+	// The GETs differ from the DELETE by the method only, so they are
+	// nearest, most recent first
+	if nearest := report.Results[2].Nearest; nearest[0].ID != "/orders/2" || nearest[1].ID != "/orders/1" {
+		t.Errorf("nearest = %+v", nearest)
+	}
+
+	if report := set.Verify(requests[1:]); len(report.Warnings) != 2 || !strings.Contains(report.Warnings[0], "1 requests captured since the set was registered were evicted") {
+		t.Errorf("warnings = %q, want the evicted and pending ones", report.Warnings)
+	}
+	if _, err := registry.Add("", nil, 0); err == nil {
+		t.Errorf("Add without expectations succeeded")
+	}
+}
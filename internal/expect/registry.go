@@ -0,0 +1,197 @@
+package expect
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+// maxSets bounds the registered sets; the oldest is dropped past it, so
+// tests that never delete theirs do not grow memory.
+const maxSets = 100
+
+// Set is a group of expectations registered together. Only requests
+// captured after it was registered are checked.
+type Set struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	After        uint64        `json:"after"`
+	Expectations []Expectation `json:"expectations"`
+
+	matchers []*matcher
+}
+
+// Report is the outcome of verifying a Set.
+type Report struct {
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Passed bool   `json:"passed"`
+	// Checked is the number of completed requests looked at. Pending ones
+	// are left out and counted separately.
+	Checked  int      `json:"checked"`
+	Pending  int      `json:"pending"`
+	Warnings []string `json:"warnings,omitempty"`
+	Results  []Result `json:"results"`
+}
+
+// Result is the outcome of one expectation. Nearest lists the requests
+// that came closest to matching when too few did.
+type Result struct {
+	Expectation Expectation `json:"expectation"`
+	Passed      bool        `json:"passed"`
+	Count       int         `json:"count"`
+	Message     string      `json:"message"`
+	Matches     []string    `json:"matches,omitempty"`
+	Nearest     []Near      `json:"nearest,omitempty"`
+}
+
+// Near is a request that failed an expectation, with the reasons.
+type Near struct {
+	ID         string   `json:"id"`
+	Method     string   `json:"method"`
+	URL        string   `json:"url"`
+	Status     int      `json:"status,omitempty"`
+	Mismatches []string `json:"mismatches"`
+}
+
+// Registry holds the registered sets.
+type Registry struct {
+	mu   sync.Mutex
+	sets []*Set
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Add validates and registers the expectations. after is the Seq of the
+// last request captured before the set, see RequestStore.LastSeq.
+func (r *Registry) Add(name string, expectations []Expectation, after uint64) (*Set, error) {
+	if len(expectations) == 0 {
+		return nil, fmt.Errorf("expectations: at least one is required")
+	}
+	set := &Set{
+		ID:           types.NewID(),
+		Name:         name,
+		CreatedAt:    time.Now(),
+		After:        after,
+		Expectations: expectations,
+	}
+	for i, e := range expectations {
+		m, err := compile(e)
+		if err != nil {
+			return nil, fmt.Errorf("expectations[%d].%w", i, err)
+		}
+		set.matchers = append(set.matchers, m)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sets = append(r.sets, set)
+	if len(r.sets) > maxSets {
+		r.sets = r.sets[len(r.sets)-maxSets:]
+	}
+	return set, nil
+}
+
+// Get returns the set with the given ID, or nil.
+func (r *Registry) Get(id string) *Set {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, set := range r.sets {
+		if set.ID == id {
+			return set
+		}
+	}
+	return nil
+}
+
+// List returns the registered sets, oldest first.
+func (r *Registry) List() []*Set {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.sets)
+}
+
+// Delete removes the set with the given ID and reports whether it existed.
+func (r *Registry) Delete(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.sets)
+	r.sets = slices.DeleteFunc(r.sets, func(set *Set) bool { return set.ID == id })
+	return len(r.sets) < n
+}
+
+// Verify checks the set against requests, in capture order as returned by
+// RequestStore.GetRequests.
+func (s *Set) Verify(requests []*types.RequestLog) *Report {
+	report := &Report{ID: s.ID, Name: s.Name, Passed: true}
+
+	var completed []*types.RequestLog
+	for _, req := range requests {
+		if req.Seq <= s.After {
+			continue
+		}
+		if req.Pending {
+			report.Pending++
+			continue
+		}
+		completed = append(completed, req)
+	}
+	report.Checked = len(completed)
+	if len(requests) > 0 && requests[0].Seq > s.After+1 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d requests captured since the set was registered were evicted, raise store.max_requests", requests[0].Seq-s.After-1))
+	}
+	if report.Pending > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d requests were still waiting for the upstream", report.Pending))
+	}
+
+	for _, m := range s.matchers {
+		result := Result{Expectation: m.Expectation}
+		var nearest []Near
+		for _, req := range completed {
+			diffs := m.mismatches(req)
+			if len(diffs) == 0 {
+				result.Matches = append(result.Matches, req.ID)
+				continue
+			}
+			near := Near{ID: req.ID, Method: req.Method, URL: req.IncomingURL, Mismatches: diffs}
+			if req.Response != nil {
+				near.Status = req.Response.StatusCode
+			}
+			nearest = append(nearest, near)
+		}
+
+		result.Count = len(result.Matches)
+		result.Passed = result.Count >= m.min && (m.max < 0 || result.Count <= m.max)
+		result.Message = fmt.Sprintf("%s matched, expected %s", countRequests(result.Count), m.describe())
+		if result.Count < m.min {
+			// Fewest differences first, most recent first among equals
+			slices.Reverse(nearest)
+			sort.SliceStable(nearest, func(i, j int) bool {
+				return len(nearest[i].Mismatches) < len(nearest[j].Mismatches)
+			})
+			result.Nearest = nearest[:min(maxNearest, len(nearest))]
+		}
+		if !result.Passed {
+			report.Passed = false
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+func countRequests(n int) string {
+	if n == 1 {
+		return "1 request"
+	}
+	return fmt.Sprintf("%d requests", n)
+}
@@ -0,0 +1,358 @@
+package filter
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+const (
+	DefaultPerPage = 25
+	MaxPerPage     = 500
+)
+
+// Filter selects captured requests. It is parsed from and encoded back to URL
+// query parameters, so a filtered view can be bookmarked:
+//
+//	service    service name, repeatable
+//	method     HTTP method, repeatable
+//	status     "404", "4xx" or "500-599"
+//	path       substring of the incoming or upstream URL
+//	path_regex "true" to read path as a regular expression
+//	from, to   RFC 3339 time, "2006-01-02T15:04" or a duration ago like "15m"
+//	header     header that must be present, repeatable; "Name:value" also
+//	           matches a substring of the value
+//	q          case-insensitive text in the request or response body
+//	page       1-based page number
+//	per_page   requests per page
+type Filter struct {
+	Services  []string
+	Methods   []string
+	Status    string
+	Path      string
+	PathRegex bool
+	From      string
+	To        string
+	Headers   []string
+	Text      string
+	Page      int
+	PerPage   int
+
+	statusMin, statusMax int
+	pathRe               *regexp.Regexp
+	from, to             time.Time
+}
+
+// Parse reads a filter from query parameters. Invalid values are reported
+// with the name of the parameter.
+func Parse(query url.Values) (*Filter, error) {
+	f := &Filter{
+		Services:  nonEmpty(query["service"]),
+		Methods:   nonEmpty(query["method"]),
+		Status:    strings.TrimSpace(query.Get("status")),
+		Path:      query.Get("path"),
+		PathRegex: query.Get("path_regex") == "true",
+		From:      strings.TrimSpace(query.Get("from")),
+		To:        strings.TrimSpace(query.Get("to")),
+		Headers:   nonEmpty(query["header"]),
+		Text:      query.Get("q"),
+		Page:      1,
+		PerPage:   DefaultPerPage,
+	}
+	for i, method := range f.Methods {
+		f.Methods[i] = strings.ToUpper(method)
+	}
+
+	var err error
+	if f.Status != "" {
+		if f.statusMin, f.statusMax, err = ParseStatus(f.Status); err != nil {
+			return nil, fmt.Errorf("status: %w", err)
+		}
+	}
+	if f.PathRegex && f.Path != "" {
+		if f.pathRe, err = regexp.Compile(f.Path); err != nil {
+			return nil, fmt.Errorf("path: %w", err)
+		}
+	}
+	now := time.Now()
+	if f.From != "" {
+		if f.from, err = parseTime(f.From, now); err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+	}
+	if f.To != "" {
+		if f.to, err = parseTime(f.To, now); err != nil {
+			return nil, fmt.Errorf("to: %w", err)
+		}
+	}
+	if page := query.Get("page"); page != "" {
+		if f.Page, err = strconv.Atoi(page); err != nil || f.Page < 1 {
+			return nil, fmt.Errorf("page: must be a positive number")
+		}
+	}
+	if perPage := query.Get("per_page"); perPage != "" {
+		if f.PerPage, err = strconv.Atoi(perPage); err != nil || f.PerPage < 1 {
+			return nil, fmt.Errorf("per_page: must be a positive number")
+		}
+		f.PerPage = min(f.PerPage, MaxPerPage)
+	}
+
+	return f, nil
+}
+
+// ParseTerms reads a filter typed as space separated name=value terms using
+// the query parameter names, like `method=post status=5xx q="no funds"`.
+// Words without a name match the path.
+func ParseTerms(s string) (*Filter, error) {
+	terms, err := splitTerms(s)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	var words []string
+	for _, term := range terms {
+		name, value, ok := strings.Cut(term, "=")
+		if !ok {
+			words = append(words, term)
+			continue
+		}
+		switch name {
+		case "service", "method", "status", "path", "path_regex", "from", "to", "header", "q":
+			query.Add(name, value)
+		default:
+			return nil, fmt.Errorf("unknown filter %q", name)
+		}
+	}
+	if len(words) > 0 {
+		if query.Has("path") {
+			return nil, fmt.Errorf("path: set both as a word and as path=")
+		}
+		query.Set("path", strings.Join(words, " "))
+	}
+	return Parse(query)
+}
+
+// splitTerms splits s at spaces outside double quotes, and drops the quotes.
+func splitTerms(s string) ([]string, error) {
+	var (
+		terms  []string
+		term   strings.Builder
+		quoted bool
+	)
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if term.Len() > 0 {
+				terms = append(terms, term.String())
+				term.Reset()
+			}
+		default:
+			term.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+	return terms, nil
+}
+
+// Query encodes the filter, without the page, as query parameters.
+func (f *Filter) Query() url.Values {
+	query := url.Values{}
+	for _, service := range f.Services {
+		query.Add("service", service)
+	}
+	for _, method := range f.Methods {
+		query.Add("method", method)
+	}
+	set := func(key, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	set("status", f.Status)
+	set("path", f.Path)
+	if f.PathRegex {
+		query.Set("path_regex", "true")
+	}
+	set("from", f.From)
+	set("to", f.To)
+	for _, header := range f.Headers {
+		query.Add("header", header)
+	}
+	set("q", f.Text)
+	if f.PerPage != DefaultPerPage {
+		query.Set("per_page", strconv.Itoa(f.PerPage))
+	}
+	return query
+}
+
+// PageQuery encodes the filter for the given page.
+func (f *Filter) PageQuery(page int) string {
+	query := f.Query()
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	return query.Encode()
+}
+
+// Active reports whether any criteria are set.
+func (f *Filter) Active() bool {
+	return len(f.Services) > 0 || len(f.Methods) > 0 || f.Status != "" || f.Path != "" ||
+		f.From != "" || f.To != "" || len(f.Headers) > 0 || f.Text != ""
+}
+
+// Match reports whether req satisfies every criterion. Pending requests never
+// match a status filter.
+func (f *Filter) Match(req *types.RequestLog) bool {
+	if len(f.Services) > 0 && !contains(f.Services, req.Service) {
+		return false
+	}
+	if len(f.Methods) > 0 && !contains(f.Methods, req.Method) {
+		return false
+	}
+	if f.Status != "" {
+		if req.Response == nil || req.Response.StatusCode < f.statusMin || req.Response.StatusCode > f.statusMax {
+			return false
+		}
+	}
+	if f.Path != "" {
+		if f.pathRe != nil {
+			if !f.pathRe.MatchString(req.IncomingURL) && !f.pathRe.MatchString(req.URL) {
+				return false
+			}
+		} else if !strings.Contains(req.IncomingURL, f.Path) && !strings.Contains(req.URL, f.Path) {
+			return false
+		}
+	}
+	if !f.from.IsZero() && req.Timestamp.Before(f.from) {
+		return false
+	}
+	if !f.to.IsZero() && req.Timestamp.After(f.to) {
+		return false
+	}
+	for _, header := range f.Headers {
+		if !hasHeader(req.Headers, header) {
+			return false
+		}
+	}
+	if f.Text != "" {
+		text := bytes.ToLower([]byte(f.Text))
+		inRequest := bytes.Contains(bytes.ToLower(req.Body), text)
+		inResponse := req.Response != nil && bytes.Contains(bytes.ToLower(req.Response.Body), text)
+		if !inRequest && !inResponse {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply filters requests, keeping their order, and returns the current page
+// together with the number of matches.
+func (f *Filter) Apply(requests []*types.RequestLog) ([]*types.RequestLog, int) {
+	var matches []*types.RequestLog
+	for _, req := range requests {
+		if f.Match(req) {
+			matches = append(matches, req)
+		}
+	}
+
+	start := (f.Page - 1) * f.PerPage
+	if start >= len(matches) {
+		return nil, len(matches)
+	}
+	end := min(start+f.PerPage, len(matches))
+	return matches[start:end], len(matches)
+}
+
+// Pages returns the number of pages for total matches.
+func (f *Filter) Pages(total int) int {
+	return max(1, (total+f.PerPage-1)/f.PerPage)
+}
+
+// ParseStatus reads a status filter like "404", "4xx" or "500-599" into the
+// inclusive range of codes it matches.
+func ParseStatus(s string) (int, int, error) {
+	if len(s) == 3 && strings.HasSuffix(strings.ToLower(s), "xx") && s[0] >= '1' && s[0] <= '5' {
+		class := int(s[0]-'0') * 100
+		return class, class + 99, nil
+	}
+	if lowText, highText, ok := strings.Cut(s, "-"); ok {
+		low, err1 := strconv.Atoi(strings.TrimSpace(lowText))
+		high, err2 := strconv.Atoi(strings.TrimSpace(highText))
+		if err1 != nil || err2 != nil || low > high {
+			return 0, 0, fmt.Errorf("invalid range %q", s)
+		}
+		return low, high, nil
+	}
+	code, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("expected a code like 404, a class like 4xx or a range like 500-599")
+	}
+	return code, code, nil
+}
+
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("expected an RFC 3339 time or a duration like 15m")
+}
+
+func hasHeader(headers map[string][]string, filter string) bool {
+	name, value, withValue := strings.Cut(filter, ":")
+	name = strings.TrimSpace(name)
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	for key, values := range headers {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		if !withValue {
+			return true
+		}
+		for _, v := range values {
+			if strings.Contains(strings.ToLower(v), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
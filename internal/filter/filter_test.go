@@ -0,0 +1,237 @@
+package filter
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		status    string
+		low, high int
+		wantErr   bool
+	}{
+		{"404", 404, 404, false},
+		{"4xx", 400, 499, false},
+		{"5XX", 500, 599, false},
+		{"500-599", 500, 599, false},
+		{"200 - 204", 200, 204, false},
+		{"6xx", 0, 0, true},
+		{"599-500", 0, 0, true},
+		{"ok", 0, 0, true},
+		{"2x", 0, 0, true},
+	}
+	for _, tt := range tests {
+		low, high, err := ParseStatus(tt.status)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseStatus(%q) = %d-%d, want an error", tt.status, low, high)
+			}
+			continue
+		}
+		if err != nil || low != tt.low || high != tt.high {
+			t.Errorf("ParseStatus(%q) = %d-%d, %v, want %d-%d", tt.status, low, high, err, tt.low, tt.high)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{"empty", "", ""},
+		{"everything", "service=buda&method=post&status=2xx&path=orders&from=15m&to=2024-05-01T10:00:00Z&header=X-Tenant&q=bid&page=2&per_page=50", ""},
+		{"local time", "from=2024-05-01T10:00", ""},
+		{"path regex", "path=%5E%2Forders%2F%5Cd%2B&path_regex=true", ""},
+		{"invalid regex ignored without path_regex", "path=%5B", ""},
+		{"invalid regex", "path=%5B&path_regex=true", "path: "},
+		{"invalid status", "status=ok", "status: "},
+		{"invalid from", "from=yesterday", "from: "},
+		{"invalid to", "to=soon", "to: "},
+		{"invalid page", "page=0", "page: must be a positive number"},
+		{"invalid per_page", "per_page=x", "per_page: must be a positive number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			_, err := Parse(query)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Parse(%q) = %v", tt.query, err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("Parse(%q) = %v, want an error starting with %q", tt.query, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseNormalizes(t *testing.T) {
+	query, _ := url.ParseQuery("service=buda&service=+&method=get&method=Post&per_page=100000&status=+4xx+")
+	f, err := Parse(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f.Services, []string{"buda"}) || !reflect.DeepEqual(f.Methods, []string{"GET", "POST"}) {
+		t.Errorf("services %q, methods %q", f.Services, f.Methods)
+	}
+	if f.PerPage != MaxPerPage || f.Page != 1 || f.Status != "4xx" {
+		t.Errorf("per_page %d, page %d, status %q", f.PerPage, f.Page, f.Status)
+	}
+
+	// Query encodes the filter back, so a view can be bookmarked
+	again, err := Parse(f.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.PageQuery(1) != f.PageQuery(1) {
+		t.Errorf("PageQuery = %q after a round trip, want %q", again.PageQuery(1), f.PageQuery(1))
+	}
+	if want := "method=GET&method=POST&page=3&per_page=500&service=buda&status=4xx"; f.PageQuery(3) != want {
+		t.Errorf("PageQuery(3) = %q, want %q", f.PageQuery(3), want)
+	}
+}
+
+func TestParseTerms(t *testing.T) {
+	tests := []struct {
+		terms   string
+		want    url.Values
+		wantErr string
+	}{
+		{"", url.Values{}, ""},
+		{"method=post status=5xx", url.Values{"method": {"POST"}, "status": {"5xx"}}, ""},
+		{`q="no funds" service=buda service=ledger`, url.Values{"q": {"no funds"}, "service": {"buda", "ledger"}}, ""},
+		{"/orders  cancel", url.Values{"path": {"/orders cancel"}}, ""},
+		{`header="X-Tenant: acme"`, url.Values{"header": {"X-Tenant: acme"}}, ""},
+		{"path=/a /b", nil, "path: set both as a word and as path="},
+		{"size=10", nil, `unknown filter "size"`},
+		{`q="open`, nil, "unterminated quote"},
+		{"status=7xx", nil, "status: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.terms, func(t *testing.T) {
+			f, err := ParseTerms(tt.terms)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("ParseTerms(%q) = %v, want an error starting with %q", tt.terms, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTerms(%q) = %v", tt.terms, err)
+			}
+			if got := f.Query(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTerms(%q) = %v, want %v", tt.terms, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Now()
+	req := &types.RequestLog{
+		Service:     "buda",
+		Method:      "POST",
+		Timestamp:   now.Add(-10 * time.Minute),
+		IncomingURL: "/buda/orders/42?market=btc-clp",
+		URL:         "https://api.buda.com/api/v2/orders/42?market=btc-clp",
+		Headers:     http.Header{"X-Tenant": {"acme-corp"}},
+		Body:        []byte(`{"type":"Bid"}`),
+		Response:    &types.ResponseLog{StatusCode: 422, Body: []byte(`{"message":"No Funds"}`)},
+	}
+	pending := &types.RequestLog{Service: "buda", Method: "GET", Pending: true}
+
+	tests := []struct {
+		name  string
+		terms string
+		want  bool
+	}{
+		{"no criteria", "", true},
+		{"service", "service=BUDA", true},
+		{"other service", "service=ledger", false},
+		{"one of the methods", "method=get method=post", true},
+		{"status class", "status=4xx", true},
+		{"status range", "status=500-599", false},
+		{"incoming path", "/buda/orders", true},
+		{"upstream path", "/api/v2/orders", true},
+		{"path regex", `path=^/buda/orders/\d+ path_regex=true`, true},
+		{"path regex on the upstream url", "path=^https://api path_regex=true", true},
+		{"path regex without a match", `path=^/orders path_regex=true`, false},
+		{"from", "from=15m", true},
+		{"from later", "from=5m", false},
+		{"to", "to=5m", true},
+		{"header", "header=x-tenant", true},
+		{"header value", `header="X-Tenant: ACME"`, true},
+		{"other header value", "header=X-Tenant:globex", false},
+		{"missing header", "header=Authorization", false},
+		{"text in request", "q=bid", true},
+		{"text in response", `q="no funds"`, true},
+		{"missing text", "q=approved", false},
+		{"all criteria", `service=buda method=post status=422 /orders/42 header=X-Tenant q=funds`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseTerms(tt.terms)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Match(req); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.terms, got, tt.want)
+			}
+		})
+	}
+
+	f, _ := ParseTerms("status=2xx")
+	if f.Match(pending) {
+		t.Errorf("a pending request matched a status filter")
+	}
+}
+
+func TestApply(t *testing.T) {
+	var requests []*types.RequestLog
+	for i := 0; i < 7; i++ {
+		method := "GET"
+		if i%2 == 1 {
+			method = "POST"
+		}
+		requests = append(requests, &types.RequestLog{Seq: uint64(i), Method: method})
+	}
+
+	tests := []struct {
+		query     string
+		wantSeqs  []uint64
+		wantTotal int
+		wantPages int
+	}{
+		{"per_page=3", []uint64{0, 1, 2}, 7, 3},
+		{"per_page=3&page=3", []uint64{6}, 7, 3},
+		{"per_page=3&page=4", nil, 7, 3},
+		{"method=get&per_page=2&page=2", []uint64{4, 6}, 4, 2},
+		{"method=delete", nil, 0, 1},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		f, err := Parse(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, total := f.Apply(requests)
+		var seqs []uint64
+		for _, req := range page {
+			seqs = append(seqs, req.Seq)
+		}
+		if !reflect.DeepEqual(seqs, tt.wantSeqs) || total != tt.wantTotal || f.Pages(total) != tt.wantPages {
+			t.Errorf("Apply(%q) = %v of %d in %d pages, want %v of %d in %d", tt.query, seqs, total, f.Pages(total), tt.wantSeqs, tt.wantTotal, tt.wantPages)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/config"
+)
+
+// maxDiffs caps how many differences are reported per request.
+const maxDiffs = 50
+
+// defaultIgnoreHeaders change on every response or with the body, which is
+// compared on its own.
+var defaultIgnoreHeaders = []string{
+	"Date", "Content-Length", "Age", "Expires", "Last-Modified", "Etag",
+	"Set-Cookie", "X-Request-Id", "Cf-Ray", "Server-Timing", "X-Runtime",
+}
+
+// timeFormats are the layouts recognised when timestamps are ignored.
+var timeFormats = []string{
+	time.RFC3339Nano, time.RFC1123, time.RFC1123Z,
+	"2006-01-02T15:04:05", "2006-01-02 15:04:05", time.DateOnly,
+}
+
+// Difference is one mismatch between a golden response and the live one.
+type Difference struct {
+	// Path is "status", "header <Name>", "body" or a JSON path.
+	Path     string `json:"path"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Ignore is a compiled set of config.IgnoreRules.
+type Ignore struct {
+	headers    map[string]bool
+	paths      []*regexp.Regexp
+	timestamps bool
+}
+
+// NewIgnore merges rules, such as the suite's and a request's, on top of the
+// default ignored headers.
+func NewIgnore(rules ...config.IgnoreRules) (*Ignore, error) {
+	ignore := &Ignore{headers: map[string]bool{}}
+	for _, name := range defaultIgnoreHeaders {
+		ignore.headers[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, r := range rules {
+		for _, name := range r.Headers {
+			ignore.headers[http.CanonicalHeaderKey(name)] = true
+		}
+		for _, path := range r.Paths {
+			re, err := compilePath(path)
+			if err != nil {
+				return nil, err
+			}
+			ignore.paths = append(ignore.paths, re)
+		}
+		ignore.timestamps = ignore.timestamps || r.Timestamps
+	}
+	return ignore, nil
+}
+
+// compilePath turns a JSON path pattern into a regexp over the concrete paths
+// built while comparing, like "$.items[0].id". It also matches everything
+// below the value.
+func compilePath(path string) (*regexp.Regexp, error) {
+	pattern := regexp.QuoteMeta(path)
+	pattern = strings.ReplaceAll(pattern, `\.\.`, `(?:\.[^.\[]+|\[\d+\])*\.`)
+	pattern = strings.ReplaceAll(pattern, `\[\*\]`, `\[\d+\]`)
+	pattern = strings.ReplaceAll(pattern, `\.\*`, `\.[^.\[]+`)
+	return regexp.Compile(`^` + pattern + `(?:$|[.\[])`)
+}
+
+func (ig *Ignore) path(path string) bool {
+	for _, re := range ig.paths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Headers returns the headers that are not ignored.
+func (ig *Ignore) Headers(headers http.Header) http.Header {
+	kept := http.Header{}
+	for name, values := range headers {
+		if name = http.CanonicalHeaderKey(name); !ig.headers[name] {
+			kept[name] = values
+		}
+	}
+	return kept
+}
+
+// Compare lists the differences between the golden response and the live
+// one. JSON bodies are compared structurally and any other body as text or
+// bytes.
+func Compare(expected, actual *Response, ignore *Ignore) []Difference {
+	var diffs []Difference
+	if expected.Status != actual.Status {
+		diffs = append(diffs, Difference{Path: "status", Expected: fmt.Sprint(expected.Status), Actual: fmt.Sprint(actual.Status)})
+	}
+
+	expectedHeaders := ignore.Headers(expected.Headers)
+	actualHeaders := ignore.Headers(actual.Headers)
+	names := map[string]bool{}
+	for name := range expectedHeaders {
+		names[name] = true
+	}
+	for name := range actualHeaders {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		e := strings.Join(expectedHeaders.Values(name), ", ")
+		a := strings.Join(actualHeaders.Values(name), ", ")
+		if e != a {
+			diffs = append(diffs, Difference{Path: "header " + name, Expected: orMissing(e), Actual: orMissing(a)})
+		}
+	}
+
+	diffs = append(diffs, compareBodies(expected, actual, ignore)...)
+	if len(diffs) > maxDiffs {
+		diffs = diffs[:maxDiffs]
+	}
+	return diffs
+}
+
+func compareBodies(expected, actual *Response, ignore *Ignore) []Difference {
+	if expected.Body != nil && actual.Body != nil {
+		var e, a any
+		json.Unmarshal(expected.Body, &e)
+		json.Unmarshal(actual.Body, &a)
+		var diffs []Difference
+		compareJSON("$", e, a, ignore, &diffs)
+		return diffs
+	}
+	if expected.isText() && actual.isText() {
+		if expected.Text != actual.Text {
+			return []Difference{{Path: "body", Expected: excerpt(expected.Text), Actual: excerpt(actual.Text)}}
+		}
+		return nil
+	}
+	if !bytes.Equal(expected.Body, actual.Body) || !bytes.Equal(expected.Binary, actual.Binary) || expected.Text != actual.Text {
+		return []Difference{{Path: "body", Expected: expected.describe(), Actual: actual.describe()}}
+	}
+	return nil
+}
+
+func compareJSON(path string, e, a any, ignore *Ignore, diffs *[]Difference) {
+	if len(*diffs) >= maxDiffs || ignore.path(path) {
+		return
+	}
+
+	switch ev := e.(type) {
+	case map[string]any:
+		av, ok := a.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := map[string]bool{}
+		for k := range ev {
+			keys[k] = true
+		}
+		for k := range av {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			compareJSON(path+"."+k, lookup(ev, k), lookup(av, k), ignore, diffs)
+		}
+		return
+	case []any:
+		av, ok := a.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < len(ev) || i < len(av); i++ {
+			var ei, ai any = missing{}, missing{}
+			if i < len(ev) {
+				ei = ev[i]
+			}
+			if i < len(av) {
+				ai = av[i]
+			}
+			compareJSON(fmt.Sprintf("%s[%d]", path, i), ei, ai, ignore, diffs)
+		}
+		return
+	case string:
+		if as, ok := a.(string); ok && ignore.timestamps && isTime(ev) && isTime(as) {
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(e, a) {
+		*diffs = append(*diffs, Difference{Path: path, Expected: jsonValue(e), Actual: jsonValue(a)})
+	}
+}
+
+func isTime(s string) bool {
+	for _, layout := range timeFormats {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// missing marks a key or array element present on only one side.
+type missing struct{}
+
+func lookup(m map[string]any, key string) any {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	return missing{}
+}
+
+func jsonValue(v any) string {
+	if _, ok := v.(missing); ok {
+		return "(missing)"
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func orMissing(s string) string {
+	if s == "" {
+		return "(missing)"
+	}
+	return s
+}
+
+// excerpt shortens text bodies for the report.
+func excerpt(s string) string {
+	const max = 200
+	if len(s) > max {
+		return fmt.Sprintf("%q... (%d bytes)", s[:max], len(s))
+	}
+	return fmt.Sprintf("%q", s)
+}
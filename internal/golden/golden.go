@@ -0,0 +1,254 @@
+// Package golden records upstream responses to golden files and compares
+// live responses with them, for golden-gate verify.
+package golden
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mtavano/golden-gate/internal/config"
+)
+
+// File is the content of a golden file. The request is kept to show what
+// produced the response; suite headers are left out as they may hold
+// credentials.
+type File struct {
+	Request  Request   `json:"request"`
+	Response *Response `json:"response"`
+}
+
+type Request struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Response keeps JSON bodies as JSON and text bodies as text so golden files
+// read well in diffs. Only one of Body, Text and Binary is set.
+type Response struct {
+	Status  int             `json:"status"`
+	Headers http.Header     `json:"headers,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"`
+	Text    string          `json:"text,omitempty"`
+	Binary  []byte          `json:"binary,omitempty"`
+}
+
+func newResponse(status int, headers http.Header, body []byte) *Response {
+	resp := &Response{Status: status, Headers: headers}
+	switch {
+	case len(body) == 0:
+	case json.Valid(body):
+		resp.Body = body
+	case utf8.Valid(body):
+		resp.Text = string(body)
+	default:
+		resp.Binary = body
+	}
+	return resp
+}
+
+// isText reports whether the body, if any, is neither JSON nor binary.
+func (r *Response) isText() bool {
+	return r.Body == nil && r.Binary == nil
+}
+
+func (r *Response) describe() string {
+	switch {
+	case r.Body != nil:
+		return fmt.Sprintf("%d bytes of JSON", len(r.Body))
+	case r.Binary != nil:
+		return fmt.Sprintf("%d bytes", len(r.Binary))
+	default:
+		return fmt.Sprintf("%d bytes of text", len(r.Text))
+	}
+}
+
+// Result is the outcome of one request of a suite.
+type Result struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Status is ok, failed, recorded or error.
+	Status string       `json:"status"`
+	Error  string       `json:"error,omitempty"`
+	Diffs  []Difference `json:"diffs,omitempty"`
+}
+
+const (
+	StatusOK       = "ok"
+	StatusFailed   = "failed"
+	StatusRecorded = "recorded"
+	StatusError    = "error"
+)
+
+// Runner sends the requests of a suite.
+type Runner struct {
+	suite  *config.Suite
+	target *url.URL
+	client *http.Client
+}
+
+// NewRunner sends the requests of suite to target, or to the suite's target
+// when it is empty.
+func NewRunner(suite *config.Suite, target string) (*Runner, error) {
+	if target == "" {
+		target = suite.Target
+	}
+	if target == "" {
+		return nil, errors.New("no target, set one in the suite or with -target")
+	}
+	base, err := url.Parse(target)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("target %q is not an absolute URL", target)
+	}
+
+	return &Runner{
+		suite:  suite,
+		target: base,
+		client: &http.Client{
+			Timeout: time.Duration(suite.Timeout),
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+// Path returns the golden file of the named request.
+func (r *Runner) Path(name string) string {
+	return filepath.Join(r.suite.Dir, name+".json")
+}
+
+// Run sends req and compares the response with its golden file, or replaces
+// the golden file when update is set.
+func (r *Runner) Run(ctx context.Context, req config.SuiteRequest, update bool) Result {
+	result := Result{Name: req.Name, Method: req.Method, Path: req.Path}
+	fail := func(err error) Result {
+		result.Status = StatusError
+		result.Error = err.Error()
+		return result
+	}
+
+	ignore, err := NewIgnore(r.suite.Ignore, req.Ignore)
+	if err != nil {
+		return fail(err)
+	}
+
+	var golden File
+	if !update {
+		data, err := os.ReadFile(r.Path(req.Name))
+		if errors.Is(err, os.ErrNotExist) {
+			return fail(errors.New("no golden file, record it with -update"))
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if err := json.Unmarshal(data, &golden); err != nil || golden.Response == nil {
+			return fail(fmt.Errorf("%s: not a golden file", r.Path(req.Name)))
+		}
+	}
+
+	live, err := r.send(ctx, req)
+	if err != nil {
+		return fail(err)
+	}
+	live.Headers = ignore.Headers(live.Headers)
+
+	if update {
+		file := File{
+			Request:  Request{Method: req.Method, Path: req.Path, Body: req.Body},
+			Response: live,
+		}
+		if err := writeFile(r.Path(req.Name), file); err != nil {
+			return fail(err)
+		}
+		result.Status = StatusRecorded
+		return result
+	}
+
+	result.Diffs = Compare(golden.Response, live, ignore)
+	result.Status = StatusOK
+	if len(result.Diffs) > 0 {
+		result.Status = StatusFailed
+	}
+	return result
+}
+
+func (r *Runner) send(ctx context.Context, req config.SuiteRequest) (*Response, error) {
+	ref, err := url.Parse(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	u := *r.target
+	u.Path = strings.TrimSuffix(r.target.Path, "/") + ref.Path
+	u.RawPath = ""
+	u.RawQuery = ref.RawQuery
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, u.String(), bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Body) > 0 {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range r.suite.Headers {
+		httpReq.Header.Set(name, value)
+	}
+	for name, value := range req.Headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	resp, err := r.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(resp.StatusCode, resp.Header, body), nil
+}
+
+// writeFile writes file as indented JSON, replacing path atomically.
+func writeFile(path string, file File) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	// CreateTemp makes files only the owner can read
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
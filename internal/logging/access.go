@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+var accessLog atomic.Pointer[AccessLog]
+
+// AccessLog writes one Common or Combined Log Format line per request.
+type AccessLog struct {
+	combined bool
+	mu       sync.Mutex
+	out      io.Writer
+}
+
+// Access records a proxied request in the access log, if one is configured.
+func Access(r *http.Request, status, size int, start time.Time) {
+	if a := accessLog.Load(); a != nil {
+		a.Log(r, status, size, start)
+	}
+}
+
+func (a *AccessLog) Log(r *http.Request, status, size int, start time.Time) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	user := "-"
+	if name, _, ok := r.BasicAuth(); ok && name != "" {
+		user = name
+	}
+
+	bytes := "-"
+	if size > 0 {
+		bytes = strconv.Itoa(size)
+	}
+
+	line := fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s`,
+		host,
+		user,
+		start.Format(clfTime),
+		r.Method,
+		escape(r.RequestURI),
+		r.Proto,
+		status,
+		bytes,
+	)
+	if a.combined {
+		line += fmt.Sprintf(` "%s" "%s"`, orDash(escape(r.Referer())), orDash(escape(r.UserAgent())))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	io.WriteString(a.out, line+"\n")
+}
+
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func escape(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
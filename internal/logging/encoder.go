@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var bufferPool = buffer.NewPool()
+
+// PrettyJSONEncoder is an encoder to pretty print json outputs
+type PrettyJSONEncoder struct {
+	zapcore.Encoder
+}
+
+func (e *PrettyJSONEncoder) Clone() zapcore.Encoder {
+	return &PrettyJSONEncoder{
+		Encoder: e.Encoder.Clone(),
+	}
+}
+
+func (e *PrettyJSONEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Free()
+
+	// do pretty printing
+	var prettyJSON bytes.Buffer
+	if err := json.Indent(&prettyJSON, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+
+	newBuf := bufferPool.Get()
+	newBuf.AppendString(strings.TrimRight(prettyJSON.String(), "\n") + "\n")
+	return newBuf, nil
+}
+
+// LogfmtEncoder renders entries as key=value pairs. It wraps a JSON encoder
+// and converts its output, keeping the field order; nested objects are
+// written as compact JSON values.
+type LogfmtEncoder struct {
+	zapcore.Encoder
+}
+
+func (e *LogfmtEncoder) Clone() zapcore.Encoder {
+	return &LogfmtEncoder{
+		Encoder: e.Encoder.Clone(),
+	}
+}
+
+func (e *LogfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Free()
+
+	decoder := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	newBuf := bufferPool.Get()
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			newBuf.Free()
+			return nil, err
+		}
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			newBuf.Free()
+			return nil, err
+		}
+
+		value := string(raw)
+		var str string
+		if json.Unmarshal(raw, &str) == nil {
+			value = str
+		}
+
+		if newBuf.Len() > 0 {
+			newBuf.AppendByte(' ')
+		}
+		newBuf.AppendString(key.(string))
+		newBuf.AppendByte('=')
+		newBuf.AppendString(logfmtValue(value))
+	}
+	newBuf.AppendByte('\n')
+	return newBuf, nil
+}
+
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\\") || strings.IndexFunc(value, func(r rune) bool { return r < ' ' }) >= 0 {
+		return strconv.Quote(value)
+	}
+	return value
+}
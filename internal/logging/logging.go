@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// level is shared by every core so a reload can change it in place.
+var level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+var encoderConfig = zapcore.EncoderConfig{
+	TimeKey:        "timestamp",
+	LevelKey:       "level",
+	NameKey:        "logger",
+	CallerKey:      "caller",
+	MessageKey:     "msg",
+	StacktraceKey:  "stacktrace",
+	LineEnding:     zapcore.DefaultLineEnding,
+	EncodeLevel:    zapcore.LowercaseLevelEncoder,
+	EncodeTime:     zapcore.ISO8601TimeEncoder,
+	EncodeDuration: zapcore.StringDurationEncoder,
+	EncodeCaller:   zapcore.ShortCallerEncoder,
+}
+
+// Setup builds the application logger from cfg and installs it as zap's
+// global logger, with the standard library log package redirected to it.
+// It also opens the access log. The returned function flushes and closes
+// every output and must be called before exiting.
+func Setup(cfg config.LoggingConfig) func() error {
+	SetLevel(cfg.Level)
+
+	var (
+		syncers []zapcore.WriteSyncer
+		closers []io.Closer
+	)
+	for _, output := range cfg.Outputs {
+		w, closer := openOutput(output)
+		syncers = append(syncers, zapcore.AddSync(w))
+		closers = append(closers, closer)
+	}
+
+	var core zapcore.Core = zapcore.NewCore(newEncoder(cfg.Format), zapcore.NewMultiWriteSyncer(syncers...), level)
+	if sampling := cfg.Sampling; sampling != nil {
+		core = zapcore.NewSamplerWithOptions(core, time.Duration(sampling.Tick), sampling.Initial, sampling.Thereafter)
+	}
+
+	logger := zap.New(core, zap.AddCaller())
+	zap.ReplaceGlobals(logger)
+	zap.RedirectStdLog(logger)
+
+	if cfg.AccessLog != nil {
+		w, closer := openOutput(*cfg.AccessLog.Output)
+		accessLog.Store(&AccessLog{combined: cfg.AccessLog.Format == "combined", out: w})
+		closers = append(closers, closer)
+	}
+
+	return func() error {
+		logger.Sync()
+		var errs []error
+		for _, closer := range closers {
+			errs = append(errs, closer.Close())
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// SetLevel changes the level of the application logger. Unknown levels fall
+// back to info; the config is validated before it gets here.
+func SetLevel(name string) {
+	l, err := zapcore.ParseLevel(name)
+	if err != nil {
+		l = zapcore.InfoLevel
+	}
+	level.SetLevel(l)
+}
+
+func newEncoder(format string) zapcore.Encoder {
+	switch format {
+	case "pretty":
+		return &PrettyJSONEncoder{Encoder: zapcore.NewJSONEncoder(encoderConfig)}
+	case "logfmt":
+		return &LogfmtEncoder{Encoder: zapcore.NewJSONEncoder(encoderConfig)}
+	case "console":
+		consoleConfig := encoderConfig
+		consoleConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		return zapcore.NewConsoleEncoder(consoleConfig)
+	}
+	return zapcore.NewJSONEncoder(encoderConfig)
+}
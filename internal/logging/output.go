@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/config"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const defaultMaxSizeMB = 100
+
+// nopCloser is returned for the standard streams, which are never closed.
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// openOutput returns the writer for output and the closer that releases it.
+func openOutput(output config.LogOutput) (io.Writer, io.Closer) {
+	switch output.Type {
+	case "stderr":
+		return os.Stderr, nopCloser{}
+	case "file":
+		maxSize := output.MaxSizeMB
+		if maxSize == 0 {
+			maxSize = defaultMaxSizeMB
+		}
+		file := &lumberjack.Logger{
+			Filename:   output.Path,
+			MaxSize:    maxSize,
+			MaxBackups: output.MaxBackups,
+			MaxAge:     output.MaxAgeDays,
+			Compress:   output.Compress,
+			LocalTime:  true,
+		}
+		if output.RotateEvery == 0 {
+			return file, file
+		}
+		return file, rotateEvery(file, time.Duration(output.RotateEvery))
+	}
+	return os.Stdout, nopCloser{}
+}
+
+// timedFile rotates a file on a fixed interval, on top of lumberjack's size
+// based rotation.
+type timedFile struct {
+	*lumberjack.Logger
+	stop chan struct{}
+}
+
+func rotateEvery(file *lumberjack.Logger, interval time.Duration) *timedFile {
+	t := &timedFile{Logger: file, stop: make(chan struct{})}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				file.Rotate()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+	return t
+}
+
+func (t *timedFile) Close() error {
+	close(t.stop)
+	return t.Logger.Close()
+}
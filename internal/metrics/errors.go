@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// ErrorCategory groups upstream errors into a small set of label values:
+// timeout, canceled, dns, connection_refused, connection_reset, tls or other.
+func ErrorCategory(err error) string {
+	var (
+		dnsErr       *net.DNSError
+		netErr       net.Error
+		certErr      *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		recordErr    tls.RecordHeaderError
+		invalidCert  x509.CertificateInvalidError
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection_refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "connection_reset"
+	case errors.As(err, &certErr), errors.As(err, &authorityErr), errors.As(err, &hostnameErr),
+		errors.As(err, &recordErr), errors.As(err, &invalidCert):
+		return "tls"
+	}
+	return "other"
+}
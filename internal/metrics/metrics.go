@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "golden_gate"
+
+// maxRoutes bounds the routes inferred from paths per service, so paths with
+// slugs or names that are not templated cannot grow the series without end.
+const maxRoutes = 100
+
+// OtherRoute is the route of requests past a service's first maxRoutes
+// inferred routes.
+const OtherRoute = "other"
+
+var sizeBuckets = prometheus.ExponentialBuckets(64, 4, 10) // 64B to 16MiB
+
+// Metrics holds the Prometheus collectors for proxied traffic. It outlives
+// config reloads so counters are not reset when the router is rebuilt.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests         *prometheus.CounterVec
+	duration         *prometheus.HistogramVec
+	upstreamDuration *prometheus.HistogramVec
+	requestSize      *prometheus.HistogramVec
+	responseSize     *prometheus.HistogramVec
+	inFlight         *prometheus.GaugeVec
+	upstreamErrors   *prometheus.CounterVec
+
+	routesMu sync.Mutex
+	routes   map[string]map[string]bool
+}
+
+// Exchange is one proxied request as seen by the proxy.
+type Exchange struct {
+	Service  string
+	Route    string
+	Method   string
+	Status   int
+	Duration time.Duration
+	// Upstream is zero when the request never reached the upstream.
+	Upstream     time.Duration
+	RequestSize  int
+	ResponseSize int
+	// UpstreamErr is the error returned by the upstream round trip, if any.
+	UpstreamErr error
+}
+
+func New(store *types.RequestStore) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		routes:   map[string]map[string]bool{},
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Proxied requests by service, route, method and status class.",
+		}, []string{"service", "route", "method", "status_class"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Total time spent handling a proxied request.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"service", "route"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upstream_duration_seconds",
+			Help:      "Time until the upstream response body was read.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"service", "route"}),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_size_bytes",
+			Help:      "Size of proxied request bodies.",
+			Buckets:   sizeBuckets,
+		}, []string{"service"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "response_size_bytes",
+			Help:      "Size of response bodies written to clients.",
+			Buckets:   sizeBuckets,
+		}, []string{"service"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "requests_in_flight",
+			Help:      "Requests currently being proxied.",
+		}, []string{"service"}),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_errors_total",
+			Help:      "Failed upstream round trips by category.",
+		}, []string{"service", "category"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		m.upstreamDuration,
+		m.requestSize,
+		m.responseSize,
+		m.inFlight,
+		m.upstreamErrors,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "store_requests",
+			Help:      "Requests currently held in the capture store.",
+		}, func() float64 { return float64(store.Len()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "store_evictions_total",
+			Help:      "Requests dropped from the capture store because it was full.",
+		}, func() float64 { return float64(store.Evicted()) }),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Start marks a request to service as in flight and returns the function
+// that ends it.
+func (m *Metrics) Start(service string) func() {
+	if m == nil {
+		return func() {}
+	}
+
+	gauge := m.inFlight.WithLabelValues(service)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// Route returns the label for a route inferred from a request path to
+// service: route itself for the service's first maxRoutes distinct routes,
+// OtherRoute after that.
+func (m *Metrics) Route(service, route string) string {
+	if m == nil {
+		return route
+	}
+
+	m.routesMu.Lock()
+	defer m.routesMu.Unlock()
+
+	routes := m.routes[service]
+	if routes == nil {
+		routes = map[string]bool{}
+		m.routes[service] = routes
+	}
+	if routes[route] {
+		return route
+	}
+	if len(routes) >= maxRoutes {
+		return OtherRoute
+	}
+	routes[route] = true
+	return route
+}
+
+// Observe records a finished exchange.
+func (m *Metrics) Observe(e Exchange) {
+	if m == nil {
+		return
+	}
+
+	m.requests.WithLabelValues(e.Service, e.Route, e.Method, statusClass(e.Status)).Inc()
+	m.duration.WithLabelValues(e.Service, e.Route).Observe(e.Duration.Seconds())
+	if e.Upstream > 0 {
+		m.upstreamDuration.WithLabelValues(e.Service, e.Route).Observe(e.Upstream.Seconds())
+	}
+	m.requestSize.WithLabelValues(e.Service).Observe(float64(e.RequestSize))
+	m.responseSize.WithLabelValues(e.Service).Observe(float64(e.ResponseSize))
+	if e.UpstreamErr != nil {
+		m.upstreamErrors.WithLabelValues(e.Service, ErrorCategory(e.UpstreamErr)).Inc()
+	}
+}
+
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
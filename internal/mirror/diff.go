@@ -0,0 +1,189 @@
+package mirror
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+// maxDiffs caps how many differences are kept per exchange.
+const maxDiffs = 50
+
+// DiffKind groups differences for display.
+type DiffKind string
+
+const (
+	DiffStatus DiffKind = "status"
+	DiffHeader DiffKind = "header"
+	DiffBody   DiffKind = "body"
+)
+
+// Difference is one mismatch between the primary and mirror responses.
+type Difference struct {
+	Kind    DiffKind `json:"kind"`
+	Path    string   `json:"path"`
+	Primary string   `json:"primary"`
+	Mirror  string   `json:"mirror"`
+}
+
+// Compare lists the differences between the primary and mirror responses.
+// Headers in ignore are skipped, JSON bodies are compared structurally and
+// any other body byte for byte.
+func Compare(primary, mirror *types.ResponseLog, ignore []string) []Difference {
+	var diffs []Difference
+
+	if primary.StatusCode != mirror.StatusCode {
+		diffs = append(diffs, Difference{
+			Kind:    DiffStatus,
+			Path:    "status",
+			Primary: fmt.Sprint(primary.StatusCode),
+			Mirror:  fmt.Sprint(mirror.StatusCode),
+		})
+	}
+
+	diffs = append(diffs, compareHeaders(primary.Headers, mirror.Headers, ignore)...)
+	diffs = append(diffs, compareBodies(primary.Body, mirror.Body)...)
+
+	if len(diffs) > maxDiffs {
+		diffs = diffs[:maxDiffs]
+	}
+	return diffs
+}
+
+func compareHeaders(primary, mirror map[string][]string, ignore []string) []Difference {
+	skip := map[string]bool{}
+	for _, name := range ignore {
+		skip[http.CanonicalHeaderKey(name)] = true
+	}
+
+	// Header maps not built by net/http may use any case
+	p, m := canonicalHeaders(primary), canonicalHeaders(mirror)
+	names := map[string]bool{}
+	for name := range p {
+		names[name] = true
+	}
+	for name := range m {
+		names[name] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		if !skip[name] {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+
+	var diffs []Difference
+	for _, name := range sorted {
+		primaryValue := strings.Join(p[name], ", ")
+		mirrorValue := strings.Join(m[name], ", ")
+		if primaryValue != mirrorValue {
+			diffs = append(diffs, Difference{Kind: DiffHeader, Path: name, Primary: primaryValue, Mirror: mirrorValue})
+		}
+	}
+	return diffs
+}
+
+func canonicalHeaders(headers map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		name = http.CanonicalHeaderKey(name)
+		out[name] = append(out[name], values...)
+	}
+	return out
+}
+
+func compareBodies(primary, mirror []byte) []Difference {
+	if bytes.Equal(primary, mirror) {
+		return nil
+	}
+
+	var p, m any
+	if json.Unmarshal(primary, &p) == nil && json.Unmarshal(mirror, &m) == nil {
+		var diffs []Difference
+		compareJSON("$", p, m, &diffs)
+		return diffs
+	}
+
+	return []Difference{{
+		Kind:    DiffBody,
+		Path:    "body",
+		Primary: fmt.Sprintf("%d bytes", len(primary)),
+		Mirror:  fmt.Sprintf("%d bytes", len(mirror)),
+	}}
+}
+
+func compareJSON(path string, p, m any, diffs *[]Difference) {
+	if len(*diffs) >= maxDiffs {
+		return
+	}
+
+	switch pv := p.(type) {
+	case map[string]any:
+		mv, ok := m.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := map[string]bool{}
+		for k := range pv {
+			keys[k] = true
+		}
+		for k := range mv {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			compareJSON(path+"."+k, lookup(pv, k), lookup(mv, k), diffs)
+		}
+		return
+	case []any:
+		mv, ok := m.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < len(pv) || i < len(mv); i++ {
+			var pi, mi any = missing{}, missing{}
+			if i < len(pv) {
+				pi = pv[i]
+			}
+			if i < len(mv) {
+				mi = mv[i]
+			}
+			compareJSON(fmt.Sprintf("%s[%d]", path, i), pi, mi, diffs)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(p, m) {
+		*diffs = append(*diffs, Difference{Kind: DiffBody, Path: path, Primary: jsonValue(p), Mirror: jsonValue(m)})
+	}
+}
+
+// missing marks a key or array element present on only one side.
+type missing struct{}
+
+func lookup(m map[string]any, key string) any {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	return missing{}
+}
+
+func jsonValue(v any) string {
+	if _, ok := v.(missing); ok {
+		return "(missing)"
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
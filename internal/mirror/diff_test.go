@@ -0,0 +1,85 @@
+package mirror
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+func TestCompare(t *testing.T) {
+	response := func(status int, header http.Header, body string) *types.ResponseLog {
+		return &types.ResponseLog{StatusCode: status, Headers: header, Body: []byte(body)}
+	}
+	json := http.Header{"Content-Type": {"application/json"}}
+
+	tests := []struct {
+		name            string
+		primary, mirror *types.ResponseLog
+		ignore          []string
+		want            []Difference
+	}{
+		{"equal", response(200, json, `{"a":1}`), response(200, json, `{"a":1}`), nil, nil},
+		{"status", response(200, nil, ""), response(500, nil, ""), nil, []Difference{
+			{Kind: DiffStatus, Path: "status", Primary: "200", Mirror: "500"},
+		}},
+		{"headers", response(200, http.Header{"X-Version": {"1"}, "X-Only-Primary": {"a", "b"}}, ""),
+			response(200, http.Header{"x-version": {"2"}}, ""), nil, []Difference{
+				{Kind: DiffHeader, Path: "X-Only-Primary", Primary: "a, b", Mirror: ""},
+				{Kind: DiffHeader, Path: "X-Version", Primary: "1", Mirror: "2"},
+			}},
+		{"ignored headers", response(200, http.Header{"Date": {"Mon"}, "X-Request-Id": {"1"}}, ""),
+			response(200, http.Header{"Date": {"Tue"}, "X-Request-Id": {"2"}}, ""), []string{"date", "x-request-id"}, nil},
+		{"json formatting and key order", response(200, json, `{"a": 1, "b": [1, 2]}`), response(200, json, `{"b":[1,2],"a":1.0}`), nil, nil},
+		{"json values", response(200, json, `{"user":{"name":"ana","age":30},"tags":["a","b"],"ok":true}`),
+			response(200, json, `{"user":{"name":"bob","age":"30"},"tags":["a"],"extra":null}`), nil, []Difference{
+				{Kind: DiffBody, Path: "$.extra", Primary: "(missing)", Mirror: "null"},
+				{Kind: DiffBody, Path: "$.ok", Primary: "true", Mirror: "(missing)"},
+				{Kind: DiffBody, Path: "$.tags[1]", Primary: `"b"`, Mirror: "(missing)"},
+				{Kind: DiffBody, Path: "$.user.age", Primary: "30", Mirror: `"30"`},
+				{Kind: DiffBody, Path: "$.user.name", Primary: `"ana"`, Mirror: `"bob"`},
+			}},
+		{"json shape", response(200, json, `{"items":[1]}`), response(200, json, `{"items":{"0":1}}`), nil, []Difference{
+			{Kind: DiffBody, Path: "$.items", Primary: "[1]", Mirror: `{"0":1}`},
+		}},
+		{"json root", response(200, json, `[1,2]`), response(200, json, `[2,1]`), nil, []Difference{
+			{Kind: DiffBody, Path: "$[0]", Primary: "1", Mirror: "2"},
+			{Kind: DiffBody, Path: "$[1]", Primary: "2", Mirror: "1"},
+		}},
+		{"text", response(200, nil, "hello"), response(200, nil, "hello!"), nil, []Difference{
+			{Kind: DiffBody, Path: "body", Primary: "5 bytes", Mirror: "6 bytes"},
+		}},
+		{"json and text", response(200, nil, `{}`), response(200, nil, "<html>"), nil, []Difference{
+			{Kind: DiffBody, Path: "body", Primary: "2 bytes", Mirror: "6 bytes"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compare(tt.primary, tt.mirror, tt.ignore); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compare =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareLimit(t *testing.T) {
+	var primary, mirror []string
+	for i := 0; i < 2*maxDiffs; i++ {
+		primary = append(primary, fmt.Sprintf(`"k%03d":1`, i))
+		mirror = append(mirror, fmt.Sprintf(`"k%03d":2`, i))
+	}
+	diffs := Compare(
+		&types.ResponseLog{StatusCode: 200, Body: []byte("{" + strings.Join(primary, ",") + "}")},
+		&types.ResponseLog{StatusCode: 500, Body: []byte("{" + strings.Join(mirror, ",") + "}")},
+		nil,
+	)
+	if len(diffs) != maxDiffs {
+		t.Fatalf("Compare kept %d differences, want %d", len(diffs), maxDiffs)
+	}
+	if diffs[0].Kind != DiffStatus || diffs[1].Path != "$.k000" {
+		t.Errorf("Compare kept %+v first, want the status and then the body in key order", diffs[:2])
+	}
+}
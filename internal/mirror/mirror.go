@@ -0,0 +1,136 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/config"
+	"github.com/mtavano/golden-gate/internal/rewrite"
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+// defaultIgnoreHeaders always differ between two servers.
+var defaultIgnoreHeaders = []string{"Date"}
+
+// hopHeaders describe the client connection and are not mirrored.
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Connection", "Te",
+	"Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// Mirror shadows the traffic of one service to a secondary target.
+type Mirror struct {
+	service  string
+	target   *url.URL
+	rewriter *rewrite.Rewriter
+	client   *http.Client
+	ignore   []string
+	store    *Store
+}
+
+func New(service string, cfg *config.MirrorConfig, rewriter *rewrite.Rewriter, store *Store) (*Mirror, error) {
+	target, err := url.Parse(cfg.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(cfg.Timeout)
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &Mirror{
+		service:  service,
+		target:   target,
+		rewriter: rewriter,
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		ignore: append(append([]string(nil), defaultIgnoreHeaders...), cfg.IgnoreHeaders...),
+		store:  store,
+	}, nil
+}
+
+// Send mirrors a request in the background. The mirror request starts right
+// away; the diff waits for primaryDone, after which reqLog.Response holds the
+// primary response (or nil if the primary failed). Nothing is sent once the
+// store is shutting down.
+func (m *Mirror) Send(method string, incoming *url.URL, header http.Header, reqLog *types.RequestLog, primaryDone <-chan struct{}) {
+	if !m.store.start() {
+		return
+	}
+	go func() {
+		defer m.store.inflight.Done()
+		m.send(method, incoming, header, reqLog, primaryDone)
+	}()
+}
+
+func (m *Mirror) send(method string, incoming *url.URL, header http.Header, reqLog *types.RequestLog, primaryDone <-chan struct{}) {
+	result := &Result{
+		Service:   m.service,
+		Timestamp: time.Now(),
+		Request:   reqLog,
+	}
+
+	result.Response, result.MirrorURL, result.Error = m.do(method, incoming, header, reqLog.Body)
+	result.Duration = time.Since(result.Timestamp)
+
+	<-primaryDone
+
+	switch {
+	case result.Error != "":
+	case reqLog.Response == nil:
+		result.Error = "primary request failed"
+	default:
+		result.Diffs = Compare(reqLog.Response, result.Response, m.ignore)
+	}
+
+	m.store.Add(result)
+}
+
+func (m *Mirror) do(method string, incoming *url.URL, header http.Header, body []byte) (*types.ResponseLog, string, string) {
+	mirrorURL, err := m.rewriter.Rewrite(m.target, incoming)
+	if err != nil {
+		return nil, "", err.Error()
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), method, mirrorURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, mirrorURL.String(), err.Error()
+	}
+	req.Header = header
+	for _, name := range hopHeaders {
+		req.Header.Del(name)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		// The URL is already in the result; keep it, and any secret in its
+		// query, out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, mirrorURL.String(), err.Error()
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, mirrorURL.String(), err.Error()
+	}
+
+	return &types.ResponseLog{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header,
+		Body:       respBody,
+	}, mirrorURL.String(), ""
+}
@@ -0,0 +1,87 @@
+package mirror
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+// Result is the outcome of mirroring one request.
+type Result struct {
+	Service   string             `json:"service"`
+	Timestamp time.Time          `json:"timestamp"`
+	Request   *types.RequestLog  `json:"request"`
+	MirrorURL string             `json:"mirror_url"`
+	Response  *types.ResponseLog `json:"response,omitempty"`
+	Duration  time.Duration      `json:"duration"`
+	Error     string             `json:"error,omitempty"`
+	Diffs     []Difference       `json:"diffs,omitempty"`
+}
+
+// Mismatch reports whether the mirror failed or answered differently.
+func (r *Result) Mismatch() bool {
+	return r.Error != "" || len(r.Diffs) > 0
+}
+
+// Store keeps the most recent mirror results.
+type Store struct {
+	mu      sync.RWMutex
+	results []*Result
+	maxSize int
+
+	// inflight counts the mirror requests still running, across reloads.
+	// Once closed is set no more are started, so Wait never races an Add.
+	inflightMu sync.Mutex
+	inflight   sync.WaitGroup
+	closed     bool
+}
+
+func NewStore(maxSize int) *Store {
+	return &Store{
+		results: make([]*Result, 0),
+		maxSize: maxSize,
+	}
+}
+
+func (s *Store) Add(result *Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.results) >= s.maxSize {
+		s.results = s.results[1:]
+	}
+	s.results = append(s.results, result)
+}
+
+// start counts a new mirror request, and reports false once Wait was called.
+func (s *Store) start() bool {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.inflight.Add(1)
+	return true
+}
+
+// Wait stops new mirror requests and blocks until every one sent so far has
+// stored its result.
+func (s *Store) Wait() {
+	s.inflightMu.Lock()
+	s.closed = true
+	s.inflightMu.Unlock()
+
+	s.inflight.Wait()
+}
+
+// Results returns a snapshot of the stored results, oldest first.
+func (s *Store) Results() []*Result {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]*Result, len(s.results))
+	copy(results, s.results)
+	return results
+}
@@ -0,0 +1,34 @@
+package mirror
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestStoreWait(t *testing.T) {
+	s := NewStore(1000)
+
+	// Requests keep starting while shutdown waits; none may start after
+	// Wait returns
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.start() {
+				s.Add(&Result{})
+				s.inflight.Done()
+			}
+		}()
+	}
+	s.Wait()
+	stored := len(s.Results())
+	wg.Wait()
+
+	if n := len(s.Results()); n != stored {
+		t.Errorf("%d results stored after Wait returned", n-stored)
+	}
+	if s.start() {
+		t.Errorf("start succeeded after Wait")
+	}
+}
@@ -2,94 +2,84 @@ package proxy
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/mtavano/golden-gate/internal/contract"
+	"github.com/mtavano/golden-gate/internal/logging"
+	"github.com/mtavano/golden-gate/internal/metrics"
+	"github.com/mtavano/golden-gate/internal/mirror"
+	"github.com/mtavano/golden-gate/internal/rewrite"
+	"github.com/mtavano/golden-gate/internal/specgen"
+	"github.com/mtavano/golden-gate/internal/tracing"
 	"github.com/mtavano/golden-gate/internal/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 )
 
-type RequestLog struct {
-	Timestamp   time.Time     `json:"timestamp"`
-	Method      string        `json:"method"`
-	Path        string        `json:"path"`
-	Headers     http.Header   `json:"headers"`
-	RequestBody []byte        `json:"request_body"`
-	Response    ResponseLog   `json:"response"`
-	Duration    time.Duration `json:"duration"`
-}
-
-type ResponseLog struct {
-	StatusCode int         `json:"status_code"`
-	Headers    http.Header `json:"headers"`
-	Body       []byte      `json:"body"`
-}
-
 type Proxy struct {
 	config       *Config
 	requestStore *types.RequestStore
 	logger       *zap.Logger
-	client       *http.Client
-	logs         chan RequestLog
 }
 
 type Config struct {
+	Name       string
 	BasePrefix string
 	Target     string
+	Rewriter   *rewrite.Rewriter
+	Mirror     *mirror.Mirror
+	Contract   *contract.Validator
+	Metrics    *metrics.Metrics
+	// RequestIDHeader carries the capture id upstream; "-" disables it.
+	RequestIDHeader string
 }
 
 func NewProxy(config *Config, requestStore *types.RequestStore) *Proxy {
-	// config the encoder
-	encoderConfig := zapcore.EncoderConfig{
-		TimeKey:        "timestamp",
-		LevelKey:       "level",
-		NameKey:        "logger",
-		CallerKey:      "caller",
-		MessageKey:     "msg",
-		StacktraceKey:  "stacktrace",
-		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    zapcore.LowercaseLevelEncoder,
-		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		EncodeDuration: zapcore.SecondsDurationEncoder,
-		EncodeCaller:   zapcore.ShortCallerEncoder,
-	}
-
-	// create base encoding
-	baseEncoder := zapcore.NewJSONEncoder(encoderConfig)
-
-	// wrap the encode with custom encoder
-	prettyEncoder := &PrettyJSONEncoder{Encoder: baseEncoder}
-
-	// Create in the core with custom encoder
-	core := zapcore.NewCore(
-		prettyEncoder,
-		zapcore.AddSync(os.Stdout),
-		zapcore.InfoLevel,
-	)
-
-	// Crear el logger con el core
-	logger := zap.New(core, zap.AddCaller())
+	// Default to plain prefix stripping when no rewrite rules were compiled
+	if config.Rewriter == nil {
+		config.Rewriter, _ = rewrite.New(config.BasePrefix, nil)
+	}
 
 	return &Proxy{
 		config:       config,
 		requestStore: requestStore,
-		logger:       logger,
-		client: &http.Client{
-			Timeout: time.Duration(5*time.Second) * time.Second,
-		},
-		logs: make(chan RequestLog, 100),
+		logger:       zap.L().Named("proxy").With(zap.String("service", config.Name)),
 	}
 }
 
 func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
+	done := p.config.Metrics.Start(p.config.Name)
+	defer done()
+	recorder := &statusRecorder{ResponseWriter: w}
+	w = recorder
+
+	// Continue the caller's trace, if any
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracing.Tracer().Start(ctx, r.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLPath(r.URL.Path),
+			attribute.String("golden_gate.service", p.config.Name),
+		),
+	)
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	p.logger.Info("request received",
 		zap.String("method", r.Method),
 		zap.String("path", r.URL.Path),
@@ -109,24 +99,43 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Create the proxy director
 	proxy := httputil.NewSingleHostReverseProxy(targetURL)
 
-	var proxiedURL string
-	if strings.HasPrefix(r.URL.Path, p.config.BasePrefix) {
-		proxiedURL = targetURL.String() + strings.TrimPrefix(r.URL.Path, p.config.BasePrefix)
-	} else {
-		p.logger.Error("La ruta no comienza con el BasePrefix esperado",
+	rewrittenURL, err := p.config.Rewriter.Rewrite(targetURL, r.URL)
+	if errors.Is(err, rewrite.ErrPrefixMismatch) {
+		p.logger.Error("path does not start with the base prefix",
 			zap.String("path", r.URL.Path),
 			zap.String("basePrefix", p.config.BasePrefix),
 		)
-		http.Error(w, "Ruta no válida", http.StatusBadRequest)
+		http.Error(w, "Invalid path", http.StatusBadRequest)
 		return
 	}
+	if err != nil {
+		p.logger.Error("failed to rewrite path",
+			zap.String("path", r.URL.Path),
+			zap.Error(err),
+		)
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	proxiedURL := rewrittenURL.String()
+
+	// Tag the request with the capture id so the upstream can correlate it.
+	// The captured headers are the ones the client sent: an id it already
+	// sent is kept there but replaced upstream, and a generated one is left
+	// out.
+	id := types.NewID()
+	headers := r.Header.Clone()
+	if name := p.config.RequestIDHeader; name != "" && name != "-" {
+		r.Header.Set(name, id)
+	}
 
-	// Modify the director to capture the response and keep the path
+	// Modify the director to capture the response and apply the rewritten URL
 	originalDirector := proxy.Director
 	proxy.Director = func(req *http.Request) {
 		originalDirector(req)
 		req.Host = targetURL.Host
-		req.URL.Path = targetURL.Path + strings.TrimPrefix(r.URL.Path, p.config.BasePrefix)
+		req.URL.Path = rewrittenURL.Path
+		req.URL.RawPath = rewrittenURL.RawPath
+		req.URL.RawQuery = rewrittenURL.RawQuery
 
 		p.logger.Info("request sending",
 			zap.String("method", req.Method),
@@ -138,12 +147,17 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Create the request log with the full target URL
 	reqLog := &types.RequestLog{
-		ID:        r.Header.Get("X-Request-ID"),
-		Timestamp: time.Now(),
-		Method:    r.Method,
-		URL:       proxiedURL,
-		Headers:   r.Header,
-		Query:     r.URL.Query(),
+		ID:          id,
+		Service:     p.config.Name,
+		Timestamp:   time.Now(),
+		Method:      r.Method,
+		IncomingURL: r.URL.RequestURI(),
+		URL:         proxiedURL,
+		Headers:     headers,
+		Query:       r.URL.Query(),
+	}
+	if spanContext := span.SpanContext(); spanContext.IsValid() {
+		reqLog.TraceID = spanContext.TraceID().String()
 	}
 
 	// Read the request body
@@ -156,52 +170,122 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Modify the transport to capture the response
-	proxy.Transport = &responseTransport{
+	transport := &responseTransport{
 		originalTransport: http.DefaultTransport,
 		requestLog:        reqLog,
-		requestStore:      p.requestStore,
+		contract:          p.config.Contract,
 		logger:            p.logger,
 	}
+	proxy.Transport = transport
+
+	// Capture the request right away so it shows up while the upstream works
+	pending := *reqLog
+	pending.Pending = true
+	p.requestStore.AddRequest(&pending)
+	reqLog.Seq = pending.Seq
+
+	// Shadow the request to the mirror target while the primary runs. The
+	// channel is closed once the capture below is complete.
+	if p.config.Mirror != nil {
+		primaryDone := make(chan struct{})
+		defer close(primaryDone)
+		incoming := *r.URL
+		p.config.Mirror.Send(r.Method, &incoming, r.Header.Clone(), reqLog, primaryDone)
+	}
 
-	proxy.ServeHTTP(w, r)
+	// Replace the pending capture with the completed exchange and record the
+	// access log and metrics, also when the reverse proxy aborts the handler
+	// because the client went away
+	defer func() {
+		aborted := recover()
+		reqLog.Duration = time.Since(start)
+		reqLog.UpstreamDuration = transport.upstream
+		switch {
+		case transport.err != nil:
+			reqLog.Error = transport.err.Error()
+		case aborted == http.ErrAbortHandler:
+			reqLog.Error = "client disconnected before the response was sent"
+		case aborted != nil:
+			reqLog.Error = fmt.Sprint(aborted)
+		}
+		p.requestStore.Complete(&pending, reqLog)
 
-	// Registrar la solicitud
-	log := RequestLog{
-		Timestamp:   start,
-		Method:      r.Method,
-		Path:        r.URL.Path,
-		Headers:     r.Header,
-		RequestBody: reqLog.Body,
-		Response: ResponseLog{
-			StatusCode: 0, // This will be set in the response transport
-			Headers:    nil,
-			Body:       nil,
-		},
-		Duration: time.Since(start),
-	}
-
-	select {
-	case p.logs <- log:
-	default:
-		// Si el canal está lleno, descartar el log
-	}
+		logging.Access(r, recorder.status, recorder.size, start)
+
+		routeName := p.route(reqLog, targetURL, rewrittenURL)
+		span.SetName(r.Method + " " + routeName)
+		span.SetAttributes(
+			semconv.HTTPRoute(routeName),
+			semconv.HTTPResponseStatusCode(recorder.status),
+		)
+		switch {
+		case aborted != nil:
+			span.SetStatus(codes.Error, reqLog.Error)
+		case recorder.status >= 500:
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+
+		p.config.Metrics.Observe(metrics.Exchange{
+			Service:      p.config.Name,
+			Route:        routeName,
+			Method:       r.Method,
+			Status:       recorder.status,
+			Duration:     time.Since(start),
+			Upstream:     transport.upstream,
+			RequestSize:  len(reqLog.Body),
+			ResponseSize: recorder.size,
+			UpstreamErr:  transport.err,
+		})
+
+		if aborted != nil {
+			panic(aborted)
+		}
+	}()
+
+	proxy.ServeHTTP(w, r)
 }
 
 type responseTransport struct {
 	originalTransport http.RoundTripper
 	requestLog        *types.RequestLog
-	requestStore      *types.RequestStore
+	contract          *contract.Validator
 	logger            *zap.Logger
+
+	// upstream and err are read for metrics once the proxy returns
+	upstream time.Duration
+	err      error
 }
 
 func (t *responseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracing.Tracer().Start(req.Context(), req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.String()),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	// The outgoing headers are already a copy owned by the reverse proxy
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	start := time.Now()
 	resp, err := t.originalTransport.RoundTrip(req)
 	if err != nil {
 		t.logger.Error("failed to send request",
 			zap.Error(err),
 		)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		t.err = err
 		return nil, err
 	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
 
 	t.logger.Info("response received",
 		zap.Int("status", resp.StatusCode),
@@ -212,9 +296,13 @@ func (t *responseTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	// Read the response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		t.err = err
 		return nil, err
 	}
 	resp.Body = io.NopCloser(bytes.NewBuffer(body))
+	t.upstream = time.Since(start)
 
 	// Create response log
 	t.requestLog.Response = &types.ResponseLog{
@@ -223,12 +311,21 @@ func (t *responseTransport) RoundTrip(req *http.Request) (*http.Response, error)
 		Body:       body,
 	}
 
-	// Store the request log
-	t.requestStore.AddRequest(t.requestLog)
+	// Check the exchange against the service contract
+	if t.contract != nil {
+		t.requestLog.Operation, t.requestLog.Violations = t.contract.Validate(t.requestLog)
+	}
 
 	return resp, nil
 }
 
-func (p *Proxy) GetLogs() <-chan RequestLog {
-	return p.logs
+// route is the metrics label for a request: the contract operation's path when
+// the service has one, otherwise the upstream path with ids templated out,
+// up to the number of routes metrics keeps per service.
+func (p *Proxy) route(reqLog *types.RequestLog, target, rewritten *url.URL) string {
+	if _, path, ok := strings.Cut(reqLog.Operation, " "); ok {
+		return path
+	}
+	path := specgen.PathTemplate(strings.TrimPrefix(rewritten.Path, strings.TrimSuffix(target.Path, "/")))
+	return p.config.Metrics.Route(p.config.Name, path)
 }
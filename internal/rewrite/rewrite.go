@@ -0,0 +1,179 @@
+package rewrite
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mtavano/golden-gate/internal/config"
+)
+
+// ErrPrefixMismatch is returned when the incoming path does not start with the
+// service BasePrefix.
+var ErrPrefixMismatch = errors.New("path does not start with base prefix")
+
+var templateParam = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)(\.\.\.)?\}`)
+
+type rule struct {
+	re      *regexp.Regexp
+	replace string
+}
+
+// Rewriter maps incoming request URLs to upstream URLs for a single service.
+type Rewriter struct {
+	prefix      string
+	stripPrefix bool
+	rules       []rule
+	query       config.QueryRewrite
+}
+
+// New compiles the rewrite configuration of a service. A nil cfg keeps the
+// historical behaviour of stripping the prefix and nothing else.
+func New(prefix string, cfg *config.RewriteConfig) (*Rewriter, error) {
+	rw := &Rewriter{
+		prefix:      prefix,
+		stripPrefix: true,
+	}
+	if cfg == nil {
+		return rw, nil
+	}
+
+	if cfg.StripPrefix != nil {
+		rw.stripPrefix = *cfg.StripPrefix
+	}
+	rw.query = cfg.Query
+
+	for i, r := range cfg.Rules {
+		switch {
+		case r.Match != "" && r.From != "":
+			return nil, fmt.Errorf("rule %d: match and from are mutually exclusive", i)
+		case r.Match != "":
+			re, err := regexp.Compile(r.Match)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i, err)
+			}
+			rw.rules = append(rw.rules, rule{re: re, replace: r.Replace})
+		case r.From != "":
+			compiled, err := compileTemplate(r.From, r.To)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i, err)
+			}
+			rw.rules = append(rw.rules, compiled)
+		default:
+			return nil, fmt.Errorf("rule %d: one of match or from is required", i)
+		}
+	}
+
+	return rw, nil
+}
+
+// compileTemplate turns "/v1/users/{id}" into an anchored regex with named
+// groups and "/users/{id}/profile" into the matching ${id} replacement.
+// A trailing "{name...}" captures the rest of the path.
+func compileTemplate(from, to string) (rule, error) {
+	names := map[string]bool{}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+
+	last := 0
+	for _, loc := range templateParam.FindAllStringSubmatchIndex(from, -1) {
+		pattern.WriteString(regexp.QuoteMeta(from[last:loc[0]]))
+		name := from[loc[2]:loc[3]]
+		if names[name] {
+			return rule{}, fmt.Errorf("duplicate template parameter %q", name)
+		}
+		names[name] = true
+		if loc[4] >= 0 {
+			pattern.WriteString("(?P<" + name + ">.*)")
+		} else {
+			pattern.WriteString("(?P<" + name + ">[^/]+)")
+		}
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(from[last:]))
+	pattern.WriteString("$")
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return rule{}, err
+	}
+
+	var unknown error
+	replace := templateParam.ReplaceAllStringFunc(to, func(m string) string {
+		name := templateParam.FindStringSubmatch(m)[1]
+		if !names[name] && unknown == nil {
+			unknown = fmt.Errorf("unknown template parameter %q in %q", name, to)
+		}
+		return "${" + name + "}"
+	})
+	if unknown != nil {
+		return rule{}, unknown
+	}
+
+	return rule{re: re, replace: replace}, nil
+}
+
+// Rewrite builds the upstream URL for in against target. Rules operate on the
+// escaped path so percent-encoded segments (e.g. %2F) survive the rewrite.
+func (rw *Rewriter) Rewrite(target, in *url.URL) (*url.URL, error) {
+	path := in.EscapedPath()
+	if !strings.HasPrefix(path, rw.prefix) {
+		return nil, ErrPrefixMismatch
+	}
+	if rw.stripPrefix {
+		path = strings.TrimPrefix(path, rw.prefix)
+	}
+
+	for _, r := range rw.rules {
+		if r.re.MatchString(path) {
+			path = r.re.ReplaceAllString(path, r.replace)
+		}
+	}
+
+	out := *target
+	rawPath := joinPath(target.EscapedPath(), path)
+	unescaped, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	out.Path = unescaped
+	out.RawPath = rawPath
+	out.RawQuery = rw.rewriteQuery(in.Query()).Encode()
+	if len(rw.query.Add) == 0 && len(rw.query.Remove) == 0 && len(rw.query.Rename) == 0 {
+		// Keep the client's original encoding and parameter order.
+		out.RawQuery = in.RawQuery
+	}
+
+	return &out, nil
+}
+
+func (rw *Rewriter) rewriteQuery(q url.Values) url.Values {
+	for _, key := range rw.query.Remove {
+		q.Del(key)
+	}
+	for from, to := range rw.query.Rename {
+		if values, ok := q[from]; ok {
+			q.Del(from)
+			q[to] = append(q[to], values...)
+		}
+	}
+	for key, value := range rw.query.Add {
+		q.Set(key, value)
+	}
+	return q
+}
+
+func joinPath(base, path string) string {
+	if path == "" {
+		return base
+	}
+	if strings.HasSuffix(base, "/") && strings.HasPrefix(path, "/") {
+		return base + path[1:]
+	}
+	if base != "" && !strings.HasSuffix(base, "/") && !strings.HasPrefix(path, "/") {
+		return base + "/" + path
+	}
+	return base + path
+}
@@ -0,0 +1,118 @@
+package rewrite
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mtavano/golden-gate/internal/config"
+)
+
+func TestRewrite(t *testing.T) {
+	keep := false
+
+	tests := []struct {
+		name   string
+		prefix string
+		target string
+		cfg    *config.RewriteConfig
+		in     string
+		want   string
+	}{
+		{"strip prefix", "/buda", "https://api.example.com", nil, "/buda/orders/1", "https://api.example.com/orders/1"},
+		{"target path", "/buda", "https://api.example.com/api/v2/", nil, "/buda/orders", "https://api.example.com/api/v2/orders"},
+		{"prefix only", "/buda", "https://api.example.com/api", nil, "/buda", "https://api.example.com/api"},
+		{"keep prefix", "/buda", "https://api.example.com", &config.RewriteConfig{StripPrefix: &keep}, "/buda/orders", "https://api.example.com/buda/orders"},
+		{"escaped slash", "/files", "https://api.example.com", nil, "/files/a%2Fb.txt", "https://api.example.com/a%2Fb.txt"},
+		{"escaped slash through a template", "/files", "https://api.example.com", &config.RewriteConfig{
+			Rules: []config.RewriteRule{{From: "/{name}", To: "/v1/objects/{name}"}},
+		}, "/files/a%2Fb.txt", "https://api.example.com/v1/objects/a%2Fb.txt"},
+		{"template", "/buda", "https://api.example.com", &config.RewriteConfig{
+			Rules: []config.RewriteRule{{From: "/users/{id}/orders/{order}", To: "/orders/{order}/owner/{id}"}},
+		}, "/buda/users/7/orders/42", "https://api.example.com/orders/42/owner/7"},
+		{"template rest of path", "/buda", "https://api.example.com", &config.RewriteConfig{
+			Rules: []config.RewriteRule{{From: "/v1/{rest...}", To: "/api/v2/{rest}"}},
+		}, "/buda/v1/markets/btc-clp/ticker", "https://api.example.com/api/v2/markets/btc-clp/ticker"},
+		{"template one segment", "/buda", "https://api.example.com", &config.RewriteConfig{
+			Rules: []config.RewriteRule{{From: "/users/{id}", To: "/accounts/{id}"}},
+		}, "/buda/users/7/orders", "https://api.example.com/users/7/orders"},
+		{"regex", "/buda", "https://api.example.com", &config.RewriteConfig{
+			Rules: []config.RewriteRule{{Match: `^/v(\d+)/(.*)$`, Replace: "/api/$2/v$1"}},
+		}, "/buda/v3/markets", "https://api.example.com/api/markets/v3"},
+		{"rules in order", "/buda", "https://api.example.com", &config.RewriteConfig{
+			Rules: []config.RewriteRule{
+				{From: "/a/{x}", To: "/b/{x}"},
+				{Match: `^/b/`, Replace: "/c/"},
+			},
+		}, "/buda/a/1", "https://api.example.com/c/1"},
+		{"query untouched", "/buda", "https://api.example.com", nil, "/buda/orders?b=2&a=1&a=0", "https://api.example.com/orders?b=2&a=1&a=0"},
+		{"query add", "/buda", "https://api.example.com", &config.RewriteConfig{
+			Query: config.QueryRewrite{Add: map[string]string{"version": "2", "page": "1"}},
+		}, "/buda/orders?page=3", "https://api.example.com/orders?page=1&version=2"},
+		{"query remove", "/buda", "https://api.example.com", &config.RewriteConfig{
+			Query: config.QueryRewrite{Remove: []string{"debug", "missing"}},
+		}, "/buda/orders?debug=1&page=3", "https://api.example.com/orders?page=3"},
+		{"query rename", "/buda", "https://api.example.com", &config.RewriteConfig{
+			Query: config.QueryRewrite{Rename: map[string]string{"p": "page"}},
+		}, "/buda/orders?p=3&page=1", "https://api.example.com/orders?page=1&page=3"},
+		{"query remove, rename and add", "/buda", "https://api.example.com", &config.RewriteConfig{
+			Query: config.QueryRewrite{
+				Remove: []string{"token"},
+				Rename: map[string]string{"q": "query"},
+				Add:    map[string]string{"source": "gg"},
+			},
+		}, "/buda/search?q=btc&token=t", "https://api.example.com/search?query=btc&source=gg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw, err := New(tt.prefix, tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			target, _ := url.Parse(tt.target)
+			in, _ := url.Parse(tt.in)
+			got, err := rw.Rewrite(target, in)
+			if err != nil {
+				t.Fatalf("Rewrite(%q) = %v", tt.in, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Rewrite(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewritePrefixMismatch(t *testing.T) {
+	rw, err := New("/buda", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _ := url.Parse("https://api.example.com")
+	in, _ := url.Parse("/other/orders")
+	if _, err := rw.Rewrite(target, in); !errors.Is(err, ErrPrefixMismatch) {
+		t.Errorf("Rewrite = %v, want ErrPrefixMismatch", err)
+	}
+}
+
+func TestNewInvalidRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    config.RewriteRule
+		wantErr string
+	}{
+		{"match and from", config.RewriteRule{Match: "^/a", From: "/a"}, "mutually exclusive"},
+		{"neither", config.RewriteRule{Replace: "/b"}, "one of match or from is required"},
+		{"bad regex", config.RewriteRule{Match: "("}, "missing closing )"},
+		{"duplicate parameter", config.RewriteRule{From: "/{id}/{id}", To: "/{id}"}, `duplicate template parameter "id"`},
+		{"unknown parameter", config.RewriteRule{From: "/{id}", To: "/{name}"}, `unknown template parameter "name"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New("/buda", &config.RewriteConfig{Rules: []config.RewriteRule{tt.rule}})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ListenAndServe serves on addr until ctx is cancelled. It then stops
+// accepting connections, cancels streaming requests (WebSocket upgrades,
+// event streams and the capture stream) so they close cleanly, and waits up to drainTimeout for
+// in-flight requests and mirror requests to finish.
+func (s *Server) ListenAndServe(ctx context.Context, addr string, drainTimeout time.Duration) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener, drainTimeout)
+}
+
+// Serve is ListenAndServe on an existing listener, which it closes.
+func (s *Server) Serve(ctx context.Context, listener net.Listener, drainTimeout time.Duration) error {
+	httpServer := &http.Server{
+		Handler: s,
+	}
+	httpServer.RegisterOnShutdown(s.stopStreams)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	zap.L().Info("shutting down, draining in-flight requests", zap.Duration("timeout", drainTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	err := httpServer.Shutdown(shutdownCtx)
+
+	// Hijacked connections are not tracked by Shutdown, wait for them here,
+	// along with mirror requests so their diffs are not lost.
+	streamsDone := make(chan struct{})
+	go func() {
+		s.streams.Wait()
+		s.mirrorStore.Wait()
+		close(streamsDone)
+	}()
+	select {
+	case <-streamsDone:
+	case <-shutdownCtx.Done():
+		err = shutdownCtx.Err()
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		zap.L().Warn("drain timeout reached, closing remaining connections")
+		return httpServer.Close()
+	}
+	return err
+}
+
+// trackStream registers a long-lived request so shutdown can cancel it and
+// wait for it to finish. The returned func must be called when it ends.
+func (s *Server) trackStream(r *http.Request) (*http.Request, func()) {
+	ctx, cancel := context.WithCancel(r.Context())
+	stop := context.AfterFunc(s.streamCtx, cancel)
+	s.streams.Add(1)
+
+	return r.WithContext(ctx), func() {
+		stop()
+		cancel()
+		s.streams.Done()
+	}
+}
+
+func isStreaming(r *http.Request) bool {
+	if strings.EqualFold(r.Header.Get("Connection"), "upgrade") || r.Header.Get("Upgrade") != "" {
+		return true
+	}
+	if r.URL.Path == captureStreamPath {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mtavano/golden-gate/internal/admin"
+	"github.com/mtavano/golden-gate/internal/alert"
+	"github.com/mtavano/golden-gate/internal/auth"
+	"github.com/mtavano/golden-gate/internal/config"
+	"github.com/mtavano/golden-gate/internal/contract"
+	"github.com/mtavano/golden-gate/internal/dashboard"
+	"github.com/mtavano/golden-gate/internal/logging"
+	"github.com/mtavano/golden-gate/internal/metrics"
+	"github.com/mtavano/golden-gate/internal/mirror"
+	"github.com/mtavano/golden-gate/internal/proxy"
+	"github.com/mtavano/golden-gate/internal/rewrite"
+	"github.com/mtavano/golden-gate/internal/snippet"
+	"github.com/mtavano/golden-gate/internal/types"
+	"go.uber.org/zap"
+)
+
+// AdminTokenEnv holds a bearer token with the admin role. Without configured
+// authentication it is the only way into the admin API, which is disabled
+// when it is unset.
+const AdminTokenEnv = "GOLDEN_GATE_ADMIN_TOKEN"
+
+const (
+	adminPath = "/api/admin"
+	// captureStreamPath never ends on its own, so shutdown must cancel it.
+	captureStreamPath = adminPath + "/captures/stream"
+)
+
+// Server owns the active router and swaps it atomically whenever the
+// configuration is reloaded. Requests already being served keep the router
+// they started with, and the request store outlives every reload.
+type Server struct {
+	configPath   string
+	requestStore *types.RequestStore
+	mirrorStore  *mirror.Store
+	contracts    *contract.Stats
+	metrics      *metrics.Metrics
+	alerts       *alert.Manager
+	dashboard    *dashboard.Handler
+	admin        *admin.Handler
+	handler      atomic.Pointer[http.Handler]
+
+	// sessionSecret signs session cookies when the config has no secret. It
+	// is kept across reloads so they do not sign everyone out.
+	sessionSecret []byte
+
+	// streamCtx is cancelled when shutdown starts so streaming requests
+	// end instead of holding the drain open.
+	streamCtx   context.Context
+	stopStreams context.CancelFunc
+	streams     sync.WaitGroup
+
+	// override adjusts every reloaded config like the command-line flags
+	// adjusted the first one.
+	override func(cfg *config.Config)
+
+	// updateMu serialises reloads and admin updates so they never race on
+	// the config file or the active config.
+	updateMu sync.Mutex
+
+	mu         sync.RWMutex
+	config     *config.Config
+	reloadedAt time.Time
+	reloadErr  error
+}
+
+func New(configPath string, cfg *config.Config, requestStore *types.RequestStore) (*Server, error) {
+	s := &Server{
+		configPath:   configPath,
+		requestStore: requestStore,
+		mirrorStore:  mirror.NewStore(cfg.Store.MaxRequests),
+		contracts:    contract.NewStats(),
+		metrics:      metrics.New(requestStore),
+		alerts:       alert.NewManager(requestStore),
+		config:       cfg,
+		reloadedAt:   time.Now(),
+	}
+	s.sessionSecret = make([]byte, 32)
+	rand.Read(s.sessionSecret)
+	s.streamCtx, s.stopStreams = context.WithCancel(context.Background())
+	s.dashboard = dashboard.NewHandler(requestStore, s.mirrorStore, s.contracts, s.alerts, s)
+	s.admin = admin.NewHandler(s, requestStore)
+
+	router, err := s.buildRouter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.handler.Store(&router)
+	s.alerts.Configure(cfg.Alerts)
+
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isStreaming(r) {
+		var done func()
+		r, done = s.trackStream(r)
+		defer done()
+	}
+
+	(*s.handler.Load()).ServeHTTP(w, r)
+}
+
+// Override applies fn to every config reloaded from then on, so settings
+// given on the command line keep precedence over the file. It must be called
+// before the server starts watching the file.
+func (s *Server) Override(fn func(cfg *config.Config)) {
+	s.override = fn
+}
+
+// Reload reads and validates the config file and, only if everything is
+// valid, replaces the active router. On failure the previous router stays
+// in place and the error is reported through LastReload.
+func (s *Server) Reload() error {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	cfg, err := config.LoadConfig(s.configPath)
+	var router http.Handler
+	if err == nil {
+		if s.override != nil {
+			s.override(cfg)
+		}
+		router, err = s.buildRouter(cfg)
+	}
+
+	s.mu.Lock()
+	previous := s.config
+	s.reloadedAt = time.Now()
+	s.reloadErr = err
+	if err == nil {
+		s.config = cfg
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	logging.SetLevel(cfg.Logging.Level)
+	if cfg.Server.Listen != previous.Server.Listen || cfg.Server.ShutdownTimeout != previous.Server.ShutdownTimeout || cfg.Store != previous.Store {
+		zap.L().Warn("changes to the server and store sections take effect after a restart")
+	}
+
+	s.handler.Store(&router)
+	s.alerts.Configure(cfg.Alerts)
+	return nil
+}
+
+// Config returns a copy of the active configuration.
+func (s *Server) Config() *config.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Clone()
+}
+
+// Update applies fn to a copy of the active configuration, validates the
+// result, persists it to the config file and swaps the router. Nothing
+// changes if any of those steps fail.
+func (s *Server) Update(fn func(cfg *config.Config) error) error {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	cfg := s.Config()
+	if err := fn(cfg); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	router, err := s.buildRouter(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := config.SaveConfig(s.configPath, cfg); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.config = cfg
+	s.reloadedAt = time.Now()
+	s.reloadErr = nil
+	s.mu.Unlock()
+
+	s.handler.Store(&router)
+	s.alerts.Configure(cfg.Alerts)
+	return nil
+}
+
+// RunAlerts evaluates the alert rules over the captured traffic until ctx
+// is cancelled.
+func (s *Server) RunAlerts(ctx context.Context) {
+	s.alerts.Run(ctx)
+}
+
+// LastReload returns when the config was last (re)loaded and the error of
+// that attempt, if it failed.
+func (s *Server) LastReload() (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reloadedAt, s.reloadErr
+}
+
+func (s *Server) buildRouter(cfg *config.Config) (http.Handler, error) {
+	r := mux.NewRouter()
+	authn := auth.New(cfg, s.sessionSecret, os.Getenv(AdminTokenEnv))
+
+	// Set up the dashboard
+	if cfg.DashboardEnabled() {
+		s.dashboard.Register(r, cfg.Dashboard.Path, authn)
+	}
+
+	// Set up the admin API
+	s.admin.Register(r.PathPrefix(adminPath).Subrouter(), authn)
+
+	// Prometheus metrics
+	r.Handle("/metrics", s.metrics.Handler())
+
+	// Code snippets for captured requests
+	r.Handle("/api/snippets", snippet.Handler())
+
+	// Set up proxies for each service
+	for name, serviceConfig := range cfg.Services {
+		if serviceConfig.Disabled {
+			continue
+		}
+
+		rewriter, err := rewrite.New(serviceConfig.BasePrefix, serviceConfig.Rewrite)
+		if err != nil {
+			return nil, &config.ValidationError{
+				Errs: []error{fmt.Errorf("services.%s.rewrite: %w", name, err)},
+			}
+		}
+
+		var shadow *mirror.Mirror
+		if serviceConfig.Mirror != nil {
+			shadow, err = mirror.New(name, serviceConfig.Mirror, rewriter, s.mirrorStore)
+			if err != nil {
+				return nil, &config.ValidationError{
+					Errs: []error{fmt.Errorf("services.%s.mirror: %w", name, err)},
+				}
+			}
+		}
+
+		var validator *contract.Validator
+		if serviceConfig.OpenAPI != "" {
+			specPath := serviceConfig.OpenAPI
+			if !filepath.IsAbs(specPath) {
+				specPath = filepath.Join(filepath.Dir(s.configPath), specPath)
+			}
+			validator, err = contract.New(name, specPath, serviceConfig.Target, s.contracts)
+			if err != nil {
+				return nil, &config.ValidationError{
+					Errs: []error{fmt.Errorf("services.%s.openapi: %w", name, err)},
+				}
+			}
+		}
+
+		proxyConfig := &proxy.Config{
+			Name:            name,
+			BasePrefix:      serviceConfig.BasePrefix,
+			Target:          serviceConfig.Target,
+			Rewriter:        rewriter,
+			Mirror:          shadow,
+			Contract:        validator,
+			Metrics:         s.metrics,
+			RequestIDHeader: cfg.Server.RequestIDHeader,
+		}
+		proxyHandler := proxy.NewProxy(proxyConfig, s.requestStore)
+		r.PathPrefix(serviceConfig.BasePrefix).Handler(proxyHandler)
+	}
+
+	zap.L().Info("services loaded",
+		zap.Int("count", len(cfg.Services)),
+		zap.String("config", s.configPath),
+	)
+	return r, nil
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mtavano/golden-gate/internal/config"
+	"go.uber.org/zap"
+)
+
+// reloadDebounce groups the bursts of events editors emit on save.
+const reloadDebounce = 250 * time.Millisecond
+
+// Watch reloads the configuration when the config file or a file in its
+// include directory changes, or the process receives SIGHUP, until ctx is
+// cancelled.
+func (s *Server) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Watch the directory rather than the file so atomic saves (write to a
+	// temp file and rename) are picked up too.
+	configFile, err := filepath.Abs(s.configPath)
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(configFile)); err != nil {
+		return err
+	}
+	// A missing include directory is added once it is created
+	includeDir := filepath.Join(filepath.Dir(configFile), config.IncludeDir)
+	if err := watcher.Add(includeDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	timer := time.NewTimer(reloadDebounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			zap.L().Info("SIGHUP received, reloading config")
+			s.reload()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			name := filepath.Clean(event.Name)
+			if name == includeDir && event.Op&fsnotify.Create != 0 {
+				// The include directory was created after startup, maybe
+				// with files already in it
+				if err := watcher.Add(includeDir); err != nil {
+					zap.L().Error("cannot watch the include directory", zap.String("dir", includeDir), zap.Error(err))
+				}
+				timer.Reset(reloadDebounce)
+				continue
+			}
+			if name != configFile && filepath.Dir(name) != includeDir {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+				timer.Reset(reloadDebounce)
+			}
+		case <-timer.C:
+			zap.L().Info("config file changed, reloading")
+			s.reload()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			zap.L().Error("config watcher failed", zap.Error(err))
+		}
+	}
+}
+
+func (s *Server) reload() {
+	if err := s.Reload(); err != nil {
+		zap.L().Error("config reload failed, keeping previous config", zap.Error(err))
+	}
+}
@@ -0,0 +1,49 @@
+package snippet
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+// Handler serves POST /api/snippets?lang=go&omit_secrets=true with a
+// captured request as the JSON body, and GET to list the languages.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			languages := make([]map[string]string, 0, len(Languages))
+			for _, lang := range Languages {
+				languages = append(languages, map[string]string{"id": string(lang), "label": lang.Label()})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(languages)
+		case http.MethodPost:
+			var req types.RequestLog
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			lang := Language(r.URL.Query().Get("lang"))
+			if lang == "" {
+				lang = Curl
+			}
+			omitSecrets, _ := strconv.ParseBool(r.URL.Query().Get("omit_secrets"))
+
+			code, err := Generate(lang, &req, Options{OmitSecrets: omitSecrets})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte(code))
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package snippet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// shellQuote quotes s for POSIX shells. Single quotes cannot be escaped
+// inside single quotes, so they are closed, escaped and reopened.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,+%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// powershellQuote uses a verbatim string, where ' is escaped by doubling.
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// jsString renders s as a string literal valid in both JavaScript and Python.
+func jsString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func curl(r *request) string {
+	first := "curl"
+	// --data-raw implies POST, and no body implies GET. Unlike --data-binary
+	// it sends a body starting with @ literally instead of reading a file.
+	implied := "GET"
+	if r.body != "" {
+		implied = "POST"
+	}
+	if r.method != implied {
+		first += " -X " + r.method
+	}
+
+	parts := []string{first + " " + shellQuote(r.url.String())}
+	for _, h := range r.headers {
+		parts = append(parts, "-H "+shellQuote(h.name+": "+h.value))
+	}
+	if r.body != "" {
+		parts = append(parts, "--data-raw "+shellQuote(r.body))
+	}
+
+	cmd := strings.Join(parts, " \\\n  ")
+	if r.binary {
+		cmd = "# " + binaryNote + "\n" + cmd
+	}
+	return cmd
+}
+
+func httpie(r *request) string {
+	parts := []string{"http " + r.method + " " + shellQuote(r.urlWithoutQuery())}
+	for _, pair := range r.queryPairs() {
+		parts = append(parts, shellQuote(pair[0]+"=="+pair[1]))
+	}
+	for _, h := range r.headers {
+		parts = append(parts, shellQuote(h.name+":"+h.value))
+	}
+
+	cmd := strings.Join(parts, " \\\n  ")
+	if r.body != "" {
+		cmd = "printf '%s' " + shellQuote(r.body) + " | " + cmd
+	}
+	if r.binary {
+		cmd = "# " + binaryNote + "\n" + cmd
+	}
+	return cmd
+}
+
+func goSnippet(r *request) string {
+	var b strings.Builder
+	b.WriteString("package main\n\n")
+	b.WriteString("import (\n\t\"fmt\"\n\t\"io\"\n\t\"net/http\"\n")
+	if r.body != "" {
+		b.WriteString("\t\"strings\"\n")
+	}
+	b.WriteString(")\n\n")
+	b.WriteString("func main() {\n")
+
+	body := "nil"
+	if r.body != "" {
+		fmt.Fprintf(&b, "\tbody := strings.NewReader(%s)\n", goString(r.body))
+		body = "body"
+	}
+	if r.binary {
+		fmt.Fprintf(&b, "\t// %s\n", binaryNote)
+	}
+	fmt.Fprintf(&b, "\treq, err := http.NewRequest(%s, %s, %s)\n", strconv.Quote(r.method), strconv.Quote(r.url.String()), body)
+	b.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	for _, h := range r.headers {
+		fmt.Fprintf(&b, "\treq.Header.Add(%s, %s)\n", strconv.Quote(h.name), strconv.Quote(h.value))
+	}
+	b.WriteString("\n\tresp, err := http.DefaultClient.Do(req)\n")
+	b.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	b.WriteString("\tdefer resp.Body.Close()\n\n")
+	b.WriteString("\tdata, _ := io.ReadAll(resp.Body)\n")
+	b.WriteString("\tfmt.Println(resp.Status)\n")
+	b.WriteString("\tfmt.Println(string(data))\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// goString prefers a raw string literal for readability when possible.
+func goString(s string) string {
+	if !strings.Contains(s, "`") && !strings.Contains(s, "\r") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+func python(r *request) string {
+	var b strings.Builder
+	b.WriteString("import requests\n\n")
+	if r.binary {
+		fmt.Fprintf(&b, "# %s\n", binaryNote)
+	}
+
+	args := []string{jsString(r.urlWithoutQuery())}
+	if pairs := r.queryPairs(); len(pairs) > 0 {
+		b.WriteString("params = [\n")
+		for _, pair := range pairs {
+			fmt.Fprintf(&b, "    (%s, %s),\n", jsString(pair[0]), jsString(pair[1]))
+		}
+		b.WriteString("]\n")
+		args = append(args, "params=params")
+	}
+	if len(r.headers) > 0 {
+		b.WriteString("headers = {\n")
+		for _, h := range r.headers {
+			fmt.Fprintf(&b, "    %s: %s,\n", jsString(h.name), jsString(h.value))
+		}
+		b.WriteString("}\n")
+		args = append(args, "headers=headers")
+	}
+	if r.body != "" {
+		fmt.Fprintf(&b, "data = %s\n", jsString(r.body))
+		args = append(args, "data=data.encode()")
+	}
+
+	fmt.Fprintf(&b, "\nresponse = requests.request(%s, %s)\n", jsString(r.method), strings.Join(args, ", "))
+	b.WriteString("print(response.status_code)\n")
+	b.WriteString("print(response.text)\n")
+	return b.String()
+}
+
+func javascript(r *request) string {
+	var b strings.Builder
+	if r.binary {
+		fmt.Fprintf(&b, "// %s\n", binaryNote)
+	}
+	fmt.Fprintf(&b, "const response = await fetch(%s, {\n", jsString(r.url.String()))
+	fmt.Fprintf(&b, "  method: %s,\n", jsString(r.method))
+	if len(r.headers) > 0 {
+		b.WriteString("  headers: [\n")
+		for _, h := range r.headers {
+			fmt.Fprintf(&b, "    [%s, %s],\n", jsString(h.name), jsString(h.value))
+		}
+		b.WriteString("  ],\n")
+	}
+	if r.body != "" {
+		fmt.Fprintf(&b, "  body: %s,\n", jsString(r.body))
+	}
+	b.WriteString("});\n")
+	b.WriteString("console.log(response.status);\n")
+	b.WriteString("console.log(await response.text());\n")
+	return b.String()
+}
+
+func powershell(r *request) string {
+	var b strings.Builder
+	if r.binary {
+		fmt.Fprintf(&b, "# %s\n", binaryNote)
+	}
+
+	var contentType string
+	var headers []header
+	for _, h := range r.headers {
+		// Invoke-WebRequest rejects Content-Type in -Headers.
+		if strings.EqualFold(h.name, "Content-Type") {
+			contentType = h.value
+			continue
+		}
+		headers = append(headers, h)
+	}
+
+	args := []string{"-Method " + r.method + " -Uri " + powershellQuote(r.url.String())}
+	if len(headers) > 0 {
+		b.WriteString("$headers = @{\n")
+		for _, h := range headers {
+			fmt.Fprintf(&b, "    %s = %s\n", powershellQuote(h.name), powershellQuote(h.value))
+		}
+		b.WriteString("}\n")
+		args = append(args, "-Headers $headers")
+	}
+	if contentType != "" {
+		args = append(args, "-ContentType "+powershellQuote(contentType))
+	}
+	if r.body != "" {
+		fmt.Fprintf(&b, "$body = %s\n", powershellQuote(r.body))
+		args = append(args, "-Body $body")
+	}
+
+	fmt.Fprintf(&b, "$response = Invoke-WebRequest %s\n", strings.Join(args, " `\n    "))
+	b.WriteString("$response.StatusCode\n")
+	b.WriteString("$response.Content\n")
+	return b.String()
+}
@@ -0,0 +1,190 @@
+package snippet
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+type Language string
+
+const (
+	Curl       Language = "curl"
+	HTTPie     Language = "httpie"
+	Go         Language = "go"
+	Python     Language = "python"
+	JavaScript Language = "javascript"
+	PowerShell Language = "powershell"
+)
+
+// Languages lists the supported languages in display order.
+var Languages = []Language{Curl, HTTPie, Go, Python, JavaScript, PowerShell}
+
+// Label is the human readable name of a language.
+func (l Language) Label() string {
+	switch l {
+	case Curl:
+		return "curl"
+	case HTTPie:
+		return "HTTPie"
+	case Go:
+		return "Go net/http"
+	case Python:
+		return "Python requests"
+	case JavaScript:
+		return "JavaScript fetch"
+	case PowerShell:
+		return "PowerShell"
+	}
+	return string(l)
+}
+
+type Options struct {
+	// OmitSecrets drops credential headers such as Authorization, Cookie
+	// and API keys from the snippet.
+	OmitSecrets bool
+}
+
+// skippedHeaders are set by every client on its own.
+var skippedHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Length":    true,
+	"Host":              true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Transfer-Encoding": true,
+}
+
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+var secretWords = []string{"token", "secret", "password", "api-key", "apikey", "signature", "session"}
+
+// IsSecretHeader reports whether a header usually carries credentials.
+func IsSecretHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	if secretHeaders[name] {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, word := range secretWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// request is the normalised input of every generator.
+type request struct {
+	method  string
+	url     *url.URL
+	headers []header
+	body    string
+	binary  bool
+}
+
+type header struct {
+	name  string
+	value string
+}
+
+// Generate renders req as a snippet in lang.
+func Generate(lang Language, req *types.RequestLog, opts Options) (string, error) {
+	r, err := normalize(req, opts)
+	if err != nil {
+		return "", err
+	}
+
+	switch lang {
+	case Curl:
+		return curl(r), nil
+	case HTTPie:
+		return httpie(r), nil
+	case Go:
+		return goSnippet(r), nil
+	case Python:
+		return python(r), nil
+	case JavaScript:
+		return javascript(r), nil
+	case PowerShell:
+		return powershell(r), nil
+	}
+	return "", fmt.Errorf("unsupported language %q", lang)
+}
+
+func normalize(req *types.RequestLog, opts Options) (*request, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &request{
+		method: req.Method,
+		url:    u,
+	}
+	if r.method == "" {
+		r.method = http.MethodGet
+	}
+
+	names := make([]string, 0, len(req.Headers))
+	for name := range req.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		canonical := http.CanonicalHeaderKey(name)
+		if skippedHeaders[canonical] || (opts.OmitSecrets && IsSecretHeader(canonical)) {
+			continue
+		}
+		for _, value := range req.Headers[name] {
+			r.headers = append(r.headers, header{name: canonical, value: value})
+		}
+	}
+
+	if len(req.Body) > 0 {
+		if utf8.Valid(req.Body) {
+			r.body = string(req.Body)
+		} else {
+			r.binary = true
+		}
+	}
+
+	return r, nil
+}
+
+// urlWithoutQuery is used by generators that pass the query separately.
+func (r *request) urlWithoutQuery() string {
+	u := *r.url
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+func (r *request) queryPairs() [][2]string {
+	query := r.url.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs [][2]string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			pairs = append(pairs, [2]string{key, value})
+		}
+	}
+	return pairs
+}
+
+const binaryNote = "binary request body omitted"
@@ -0,0 +1,177 @@
+package snippet
+
+import (
+	"net/http"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"a-b_c./:=@,+%", "a-b_c./:=@,+%"},
+		{"", "''"},
+		{"two words", "'two words'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"a&b", "'a&b'"},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuoteRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no POSIX shell")
+	}
+
+	quoted := []string{
+		"plain",
+		"https://api.example.com/orders?page=2&sort=asc",
+		"",
+		"two words",
+		"it's",
+		"''",
+		`{"name":"O'Brien","note":"a \"quote\""}`,
+		"$HOME `id` $(id) \\ ! * ?",
+		"@/etc/passwd",
+		"line\nbreak\ttab",
+		"ünïcode ✓",
+	}
+	for _, s := range quoted {
+		out, err := exec.Command("sh", "-c", "printf '%s' "+shellQuote(s)).Output()
+		if err != nil {
+			t.Fatalf("sh with %s: %v", shellQuote(s), err)
+		}
+		if string(out) != s {
+			t.Errorf("sh printed %q for %s, want %q", out, shellQuote(s), s)
+		}
+	}
+}
+
+func TestPowershellQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "'plain'"},
+		{"", "''"},
+		{"it's", "'it''s'"},
+		{"$env:HOME `n", "'$env:HOME `n'"},
+	}
+	for _, tt := range tests {
+		if got := powershellQuote(tt.in); got != tt.want {
+			t.Errorf("powershellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGoString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`{"a":"b"}`, "`{\"a\":\"b\"}`"},
+		{"line\nbreak", "`line\nbreak`"},
+		{"back`tick", `"back` + "`" + `tick"`},
+		{"cr\r\n", `"cr\r\n"`},
+	}
+	for _, tt := range tests {
+		if got := goString(tt.in); got != tt.want {
+			t.Errorf("goString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJSString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", `"plain"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"<script>&", `"<script>&"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"back\\slash", `"back\\slash"`},
+		{"\u2028", `"\u2028"`},
+	}
+	for _, tt := range tests {
+		if got := jsString(tt.in); got != tt.want {
+			t.Errorf("jsString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCurl(t *testing.T) {
+	tests := []struct {
+		name string
+		req  types.RequestLog
+		want string
+	}{
+		{"get", types.RequestLog{Method: "GET", URL: "https://api.example.com/orders?page=2&sort=asc"},
+			"curl 'https://api.example.com/orders?page=2&sort=asc'"},
+		{"post", types.RequestLog{Method: "POST", URL: "https://api.example.com/orders", Body: []byte(`{"name":"O'Brien"}`)},
+			"curl https://api.example.com/orders \\\n  --data-raw '{\"name\":\"O'\\''Brien\"}'"},
+		{"body starting with @", types.RequestLog{Method: "POST", URL: "https://api.example.com/upload", Body: []byte("@/etc/passwd")},
+			"curl https://api.example.com/upload \\\n  --data-raw @/etc/passwd"},
+		{"put", types.RequestLog{Method: "PUT", URL: "https://api.example.com/orders/1", Body: []byte("x=1")},
+			"curl -X PUT https://api.example.com/orders/1 \\\n  --data-raw x=1"},
+		{"delete", types.RequestLog{Method: "DELETE", URL: "https://api.example.com/orders/1"},
+			"curl -X DELETE https://api.example.com/orders/1"},
+		{"headers", types.RequestLog{Method: "GET", URL: "https://api.example.com/", Headers: http.Header{
+			"Accept":         {"application/json"},
+			"Content-Length": {"0"},
+			"X-Note":         {"it's"},
+		}}, "curl https://api.example.com/ \\\n  -H 'Accept: application/json' \\\n  -H 'X-Note: it'\\''s'"},
+		{"binary body", types.RequestLog{Method: "POST", URL: "https://api.example.com/", Body: []byte{0xff, 0xfe}},
+			"# binary request body omitted\ncurl -X POST https://api.example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Generate(Curl, &tt.req, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Generate =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateOmitSecrets(t *testing.T) {
+	req := &types.RequestLog{Method: "GET", URL: "https://api.example.com/", Headers: http.Header{
+		"Authorization": {"Bearer s3cret"},
+		"X-Api-Key":     {"k3y"},
+		"Cookie":        {"session=abc"},
+		"Accept":        {"application/json"},
+	}}
+	for _, lang := range Languages {
+		t.Run(string(lang), func(t *testing.T) {
+			got, err := Generate(lang, req, Options{OmitSecrets: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, secret := range []string{"s3cret", "k3y", "session=abc"} {
+				if strings.Contains(got, secret) {
+					t.Errorf("snippet contains %q:\n%s", secret, got)
+				}
+			}
+			if !strings.Contains(got, "application/json") {
+				t.Errorf("snippet lacks the Accept header:\n%s", got)
+			}
+		})
+	}
+	if _, err := Generate("cobol", req, Options{}); err == nil {
+		t.Errorf("Generate(cobol) succeeded, want an error")
+	}
+}
@@ -0,0 +1,87 @@
+package specgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	numericSegment = regexp.MustCompile(`^[0-9]+$`)
+	uuidSegment    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexSegment     = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	mixedSegment   = regexp.MustCompile(`^[A-Za-z0-9_-]*[0-9][A-Za-z0-9_-]*$`)
+)
+
+// pathParam is a templated segment of a normalised path.
+type pathParam struct {
+	name   string
+	format string // integer, uuid or empty for plain strings
+}
+
+// normalizePath turns "/orders/123/items/9" into
+// "/orders/{orderId}/items/{itemId}" and reports the parameters it created.
+func normalizePath(path string) (string, []pathParam) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	var params []pathParam
+	used := map[string]int{}
+	for i, segment := range segments {
+		format, ok := identifierFormat(segment)
+		if !ok {
+			continue
+		}
+
+		name := "id"
+		if i > 0 && !strings.HasPrefix(segments[i-1], "{") {
+			name = singular(segments[i-1]) + "Id"
+		}
+		used[name]++
+		if used[name] > 1 {
+			name = fmt.Sprintf("%s%d", name, used[name])
+		}
+
+		segments[i] = "{" + name + "}"
+		params = append(params, pathParam{name: name, format: format})
+	}
+
+	return "/" + strings.Join(segments, "/"), params
+}
+
+// identifierFormat reports whether a path segment looks like an identifier
+// rather than a fixed part of the route.
+func identifierFormat(segment string) (string, bool) {
+	switch {
+	case numericSegment.MatchString(segment):
+		return "integer", true
+	case uuidSegment.MatchString(segment):
+		return "uuid", true
+	case hexSegment.MatchString(segment):
+		return "", true
+	case len(segment) >= 8 && mixedSegment.MatchString(segment) && strings.ContainsAny(segment, "0123456789"):
+		// Long tokens mixing letters and digits (e.g. "ord_8f2k1x9") are ids;
+		// short ones like "v2" or "oauth2" usually are not.
+		return "", true
+	}
+	return "", false
+}
+
+func singular(word string) string {
+	word = strings.NewReplacer("-", "", "_", "").Replace(word)
+	switch {
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "ses"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// PathTemplate returns the templated form of path, e.g. "/orders/{orderId}"
+// for "/orders/123". It is used to group requests without exploding on ids.
+func PathTemplate(path string) string {
+	template, _ := normalizePath(path)
+	return template
+}
@@ -0,0 +1,173 @@
+package specgen
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// shape accumulates the JSON values seen at one position of a document so
+// they can be merged into a single schema.
+type shape struct {
+	types   map[string]int
+	objects int
+	props   map[string]*shape
+	items   *shape
+	formats map[string]int
+	strings int
+}
+
+func newShape() *shape {
+	return &shape{
+		types:   map[string]int{},
+		props:   map[string]*shape{},
+		formats: map[string]int{},
+	}
+}
+
+// addJSON adds a raw JSON document; it reports false if body is not JSON.
+func (s *shape) addJSON(body []byte) bool {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return false
+	}
+	s.add(v)
+	return true
+}
+
+func (s *shape) add(v any) {
+	switch v := v.(type) {
+	case nil:
+		s.types["null"]++
+	case bool:
+		s.types["boolean"]++
+	case float64:
+		if v == float64(int64(v)) {
+			s.types["integer"]++
+		} else {
+			s.types["number"]++
+		}
+	case string:
+		s.types["string"]++
+		s.strings++
+		if format := stringFormat(v); format != "" {
+			s.formats[format]++
+		}
+	case []any:
+		s.types["array"]++
+		if s.items == nil {
+			s.items = newShape()
+		}
+		for _, item := range v {
+			s.items.add(item)
+		}
+	case map[string]any:
+		s.types["object"]++
+		s.objects++
+		for key, value := range v {
+			prop, ok := s.props[key]
+			if !ok {
+				prop = newShape()
+				s.props[key] = prop
+			}
+			prop.add(value)
+		}
+	}
+}
+
+// addScalar adds a query or header value, guessing its type from the text.
+func (s *shape) addScalar(value string) {
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		s.types["integer"]++
+		return
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		s.types["number"]++
+		return
+	}
+	if value == "true" || value == "false" {
+		s.types["boolean"]++
+		return
+	}
+	s.add(value)
+}
+
+func (s *shape) seen() int {
+	total := 0
+	for _, n := range s.types {
+		total += n
+	}
+	return total
+}
+
+func (s *shape) schema() *openapi3.Schema {
+	schema := &openapi3.Schema{}
+
+	types := map[string]int{}
+	for typ, n := range s.types {
+		types[typ] = n
+	}
+	if types["null"] > 0 {
+		schema.Nullable = true
+		delete(types, "null")
+	}
+	if types["integer"] > 0 && types["number"] > 0 {
+		types["number"] += types["integer"]
+		delete(types, "integer")
+	}
+	if len(types) != 1 {
+		// Mixed or only-null values: leave the type open.
+		return schema
+	}
+
+	for typ := range types {
+		schema.Type = &openapi3.Types{typ}
+	}
+
+	switch {
+	case schema.Type.Is("string"):
+		for format, n := range s.formats {
+			if n == s.strings {
+				schema.Format = format
+			}
+		}
+	case schema.Type.Is("array"):
+		if s.items != nil && s.items.seen() > 0 {
+			schema.Items = openapi3.NewSchemaRef("", s.items.schema())
+		} else {
+			schema.Items = openapi3.NewSchemaRef("", &openapi3.Schema{})
+		}
+	case schema.Type.Is("object"):
+		schema.Properties = openapi3.Schemas{}
+		names := make([]string, 0, len(s.props))
+		for name := range s.props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop := s.props[name]
+			schema.Properties[name] = openapi3.NewSchemaRef("", prop.schema())
+			if prop.seen() == s.objects {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+	}
+
+	return schema
+}
+
+func stringFormat(v string) string {
+	if _, err := time.Parse(time.RFC3339, v); err == nil {
+		return "date-time"
+	}
+	if _, err := time.Parse("2006-01-02", v); err == nil {
+		return "date"
+	}
+	if uuidSegment.MatchString(v) {
+		return "uuid"
+	}
+	return ""
+}
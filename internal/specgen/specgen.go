@@ -0,0 +1,321 @@
+package specgen
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mtavano/golden-gate/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// ignoredHeaders are transport or client details that do not belong in an
+// API description.
+var ignoredHeaders = map[string]bool{
+	"Accept":            true,
+	"Accept-Encoding":   true,
+	"Accept-Language":   true,
+	"Authorization":     true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Cookie":            true,
+	"Host":              true,
+	"Origin":            true,
+	"Referer":           true,
+	"Traceparent":       true,
+	"Tracestate":        true,
+	"Baggage":           true,
+	"User-Agent":        true,
+	"X-Forwarded-For":   true,
+	"X-Forwarded-Host":  true,
+	"X-Forwarded-Proto": true,
+}
+
+// operation accumulates every captured exchange of one method and path
+// template.
+type operation struct {
+	count int
+	// withBody counts the requests that had a body.
+	withBody   int
+	pathParams []pathParam
+	query      map[string]*shape
+	headers    map[string]*shape
+	bodies     map[string]*shape
+	responses  map[int]map[string]*shape
+}
+
+func newOperation(params []pathParam) *operation {
+	return &operation{
+		pathParams: params,
+		query:      map[string]*shape{},
+		headers:    map[string]*shape{},
+		bodies:     map[string]*shape{},
+		responses:  map[int]map[string]*shape{},
+	}
+}
+
+// Generate infers an OpenAPI 3 document for service from its captured
+// requests. target is the service's upstream URL: it becomes the document's
+// server and its path is stripped from every operation path.
+func Generate(service, target string, requests []*types.RequestLog) (*openapi3.T, error) {
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	basePath := strings.TrimSuffix(targetURL.Path, "/")
+
+	operations := map[string]map[string]*operation{}
+	for _, req := range requests {
+		if req.Service != service {
+			continue
+		}
+
+		u, err := url.Parse(req.URL)
+		if err != nil {
+			continue
+		}
+		template, params := normalizePath(strings.TrimPrefix(u.Path, basePath))
+
+		if operations[template] == nil {
+			operations[template] = map[string]*operation{}
+		}
+		op, ok := operations[template][req.Method]
+		if !ok {
+			op = newOperation(params)
+			operations[template][req.Method] = op
+		} else {
+			op.mergePathParams(params)
+		}
+		op.add(req, u.Query())
+	}
+
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       service,
+			Version:     "0.0.0",
+			Description: "Inferred by Golden Gate from captured traffic.",
+		},
+		Servers: openapi3.Servers{{URL: strings.TrimSuffix(target, "/")}},
+		Paths:   openapi3.NewPaths(),
+	}
+
+	templates := make([]string, 0, len(operations))
+	for template := range operations {
+		templates = append(templates, template)
+	}
+	sort.Strings(templates)
+
+	for _, template := range templates {
+		item := &openapi3.PathItem{}
+		for method, op := range operations[template] {
+			item.SetOperation(method, op.build())
+		}
+		doc.Paths.Set(template, item)
+	}
+
+	return doc, nil
+}
+
+// MarshalJSON renders doc as indented JSON.
+func MarshalJSON(doc *openapi3.T) ([]byte, error) {
+	data, err := doc.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var indented map[string]any
+	if err := json.Unmarshal(data, &indented); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(indented, "", "  ")
+}
+
+// MarshalYAML renders doc as YAML.
+func MarshalYAML(doc *openapi3.T) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (op *operation) mergePathParams(params []pathParam) {
+	for i := range op.pathParams {
+		if i < len(params) && params[i].format != op.pathParams[i].format {
+			op.pathParams[i].format = ""
+		}
+	}
+}
+
+func (op *operation) add(req *types.RequestLog, query url.Values) {
+	op.count++
+
+	for name, values := range query {
+		if op.query[name] == nil {
+			op.query[name] = newShape()
+		}
+		op.query[name].addScalar(values[0])
+	}
+
+	for name, values := range req.Headers {
+		name = http.CanonicalHeaderKey(name)
+		if ignoredHeaders[name] || len(values) == 0 {
+			continue
+		}
+		if op.headers[name] == nil {
+			op.headers[name] = newShape()
+		}
+		op.headers[name].addScalar(values[0])
+	}
+
+	if len(req.Body) > 0 {
+		op.withBody++
+		addBody(op.bodies, req.Headers, req.Body)
+	}
+
+	if req.Response != nil {
+		status := req.Response.StatusCode
+		if op.responses[status] == nil {
+			op.responses[status] = map[string]*shape{}
+		}
+		addBody(op.responses[status], req.Response.Headers, req.Response.Body)
+	}
+}
+
+// addBody records body under its media type. Bodies that are not JSON only
+// contribute their media type.
+func addBody(bodies map[string]*shape, headers map[string][]string, body []byte) {
+	body = decodedBody(headers, body)
+	mediaType, _, err := mime.ParseMediaType(http.Header(headers).Get("Content-Type"))
+	if err != nil || mediaType == "" {
+		if len(body) == 0 {
+			return
+		}
+		mediaType = "application/octet-stream"
+	}
+
+	if bodies[mediaType] == nil {
+		bodies[mediaType] = newShape()
+	}
+	if len(body) > 0 && strings.Contains(mediaType, "json") {
+		bodies[mediaType].addJSON(body)
+	}
+}
+
+// decodedBody undoes gzip and deflate content encoding so the body can be
+// parsed. The body is returned as is when it cannot be decoded.
+func decodedBody(headers map[string][]string, body []byte) []byte {
+	var (
+		reader io.ReadCloser
+		err    error
+	)
+	switch strings.ToLower(http.Header(headers).Get("Content-Encoding")) {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// deflate is meant to be zlib wrapped, but some servers send it raw
+		if reader, err = zlib.NewReader(bytes.NewReader(body)); err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return body
+	}
+	if err != nil {
+		return body
+	}
+	defer reader.Close()
+
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+func (op *operation) build() *openapi3.Operation {
+	operation := openapi3.NewOperation()
+
+	for _, param := range op.pathParams {
+		schema := openapi3.NewStringSchema()
+		switch param.format {
+		case "integer":
+			schema = openapi3.NewIntegerSchema()
+		case "uuid":
+			schema.Format = "uuid"
+		}
+		operation.AddParameter(openapi3.NewPathParameter(param.name).WithSchema(schema))
+	}
+
+	for _, name := range sortedNames(op.query) {
+		s := op.query[name]
+		operation.AddParameter(openapi3.NewQueryParameter(name).
+			WithRequired(s.seen() == op.count).
+			WithSchema(s.schema()))
+	}
+
+	for _, name := range sortedNames(op.headers) {
+		s := op.headers[name]
+		operation.AddParameter(openapi3.NewHeaderParameter(name).
+			WithRequired(s.seen() == op.count).
+			WithSchema(s.schema()))
+	}
+
+	if len(op.bodies) > 0 {
+		body := openapi3.NewRequestBody().WithContent(buildContent(op.bodies))
+		body.Required = op.withBody == op.count
+		operation.RequestBody = &openapi3.RequestBodyRef{Value: body}
+	}
+
+	operation.Responses = openapi3.NewResponses()
+	if len(op.responses) > 0 {
+		operation.Responses.Delete("default")
+	}
+	for status, bodies := range op.responses {
+		description := http.StatusText(status)
+		if description == "" {
+			description = "Observed response"
+		}
+		response := openapi3.NewResponse().WithDescription(description)
+		if len(bodies) > 0 {
+			response.WithContent(buildContent(bodies))
+		}
+		operation.Responses.Set(strconv.Itoa(status), &openapi3.ResponseRef{Value: response})
+	}
+
+	return operation
+}
+
+func buildContent(bodies map[string]*shape) openapi3.Content {
+	content := openapi3.NewContent()
+	for mediaType, s := range bodies {
+		schema := &openapi3.Schema{}
+		if s.seen() > 0 {
+			schema = s.schema()
+		}
+		content[mediaType] = openapi3.NewMediaType().WithSchema(schema)
+	}
+	return content
+}
+
+func sortedNames(m map[string]*shape) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,267 @@
+package stats
+
+import (
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mtavano/golden-gate/internal/specgen"
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+// MaxBuckets bounds the number of points in the time series. The bucket
+// size grows from one minute as the captured time range gets longer.
+const MaxBuckets = 120
+
+// TopN is the number of slowest and largest requests kept.
+const TopN = 10
+
+var bucketSizes = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	3 * time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+}
+
+// Report aggregates a set of captured requests.
+type Report struct {
+	Total   int
+	Pending int
+	Errors  int
+	From    time.Time
+	To      time.Time
+
+	// Bucket is the width of each point in Throughput and ErrorRate.
+	Bucket     time.Duration
+	Buckets    []time.Time
+	Throughput []Series
+	ErrorRate  []float64
+
+	Statuses  []StatusCount
+	Endpoints []Endpoint
+	Slowest   []*types.RequestLog
+	Largest   []*types.RequestLog
+}
+
+// Series is a per-service time series aligned with Report.Buckets.
+type Series struct {
+	Service string
+	// PerMinute is the average number of requests per minute in each bucket.
+	PerMinute []float64
+}
+
+type StatusCount struct {
+	Code  int
+	Count int
+}
+
+// Endpoint holds latency percentiles for one normalised endpoint, e.g.
+// "GET /buda/orders/{orderId}".
+type Endpoint struct {
+	Service string
+	Method  string
+	Route   string
+	Count   int
+	Errors  int
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// IsError reports whether req failed: the upstream could not be reached or
+// answered with a 5xx status.
+func IsError(req *types.RequestLog) bool {
+	if req.Pending {
+		return false
+	}
+	return req.Error != "" || req.Response == nil || req.Response.StatusCode >= 500
+}
+
+// Compute builds a report over requests, which must be in capture order.
+// Pending requests are counted but left out of every other figure.
+func Compute(requests []*types.RequestLog) *Report {
+	report := &Report{}
+	var completed []*types.RequestLog
+	for _, req := range requests {
+		report.Total++
+		if req.Pending {
+			report.Pending++
+			continue
+		}
+		if IsError(req) {
+			report.Errors++
+		}
+		completed = append(completed, req)
+	}
+	if len(completed) == 0 {
+		return report
+	}
+
+	report.From = completed[0].Timestamp
+	report.To = completed[0].Timestamp
+	for _, req := range completed {
+		if req.Timestamp.Before(report.From) {
+			report.From = req.Timestamp
+		}
+		if req.Timestamp.After(report.To) {
+			report.To = req.Timestamp
+		}
+	}
+
+	report.timeSeries(completed)
+	report.Statuses = statuses(completed)
+	report.Endpoints = endpoints(completed)
+	report.Slowest = top(completed, func(req *types.RequestLog) int64 { return int64(req.Duration) })
+	report.Largest = top(completed, func(req *types.RequestLog) int64 { return int64(Size(req)) })
+	return report
+}
+
+// Size is the number of body bytes exchanged by req.
+func Size(req *types.RequestLog) int {
+	size := len(req.Body)
+	if req.Response != nil {
+		size += len(req.Response.Body)
+	}
+	return size
+}
+
+// Route returns the endpoint req is grouped under: the matched contract
+// operation path, or the templated incoming path.
+func Route(req *types.RequestLog) string {
+	if _, path, ok := strings.Cut(req.Operation, " "); ok {
+		return path
+	}
+	path := req.IncomingURL
+	if u, err := url.Parse(req.IncomingURL); err == nil {
+		path = u.Path
+	}
+	return specgen.PathTemplate(path)
+}
+
+func (r *Report) timeSeries(requests []*types.RequestLog) {
+	span := r.To.Sub(r.From)
+	r.Bucket = bucketSizes[len(bucketSizes)-1]
+	for _, size := range bucketSizes {
+		if span/size < MaxBuckets {
+			r.Bucket = size
+			break
+		}
+	}
+
+	start := r.From.Truncate(r.Bucket)
+	count := int(r.To.Sub(start)/r.Bucket) + 1
+	for i := range count {
+		r.Buckets = append(r.Buckets, start.Add(time.Duration(i)*r.Bucket))
+	}
+
+	perService := map[string][]float64{}
+	totals := make([]int, count)
+	errors := make([]int, count)
+	for _, req := range requests {
+		i := int(req.Timestamp.Sub(start) / r.Bucket)
+		if perService[req.Service] == nil {
+			perService[req.Service] = make([]float64, count)
+		}
+		perService[req.Service][i]++
+		totals[i]++
+		if IsError(req) {
+			errors[i]++
+		}
+	}
+
+	minutes := r.Bucket.Minutes()
+	for service, counts := range perService {
+		for i := range counts {
+			counts[i] /= minutes
+		}
+		r.Throughput = append(r.Throughput, Series{Service: service, PerMinute: counts})
+	}
+	sort.Slice(r.Throughput, func(i, j int) bool {
+		return r.Throughput[i].Service < r.Throughput[j].Service
+	})
+
+	r.ErrorRate = make([]float64, count)
+	for i := range totals {
+		if totals[i] > 0 {
+			r.ErrorRate[i] = float64(errors[i]) / float64(totals[i])
+		}
+	}
+}
+
+func statuses(requests []*types.RequestLog) []StatusCount {
+	counts := map[int]int{}
+	for _, req := range requests {
+		code := 0
+		if req.Response != nil {
+			code = req.Response.StatusCode
+		}
+		counts[code]++
+	}
+
+	statuses := make([]StatusCount, 0, len(counts))
+	for code, count := range counts {
+		statuses = append(statuses, StatusCount{Code: code, Count: count})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Code < statuses[j].Code })
+	return statuses
+}
+
+type endpointKey struct {
+	service, method, route string
+}
+
+// endpoints returns the endpoints sorted by p90, slowest first.
+func endpoints(requests []*types.RequestLog) []Endpoint {
+	durations := map[endpointKey][]time.Duration{}
+	errors := map[endpointKey]int{}
+	for _, req := range requests {
+		key := endpointKey{service: req.Service, method: req.Method, route: Route(req)}
+		durations[key] = append(durations[key], req.Duration)
+		if IsError(req) {
+			errors[key]++
+		}
+	}
+
+	endpoints := make([]Endpoint, 0, len(durations))
+	for key, values := range durations {
+		slices.Sort(values)
+		endpoints = append(endpoints, Endpoint{
+			Service: key.service,
+			Method:  key.method,
+			Route:   key.route,
+			Count:   len(values),
+			Errors:  errors[key],
+			P50:     Percentile(values, 50),
+			P90:     Percentile(values, 90),
+			P99:     Percentile(values, 99),
+			Max:     values[len(values)-1],
+		})
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].P90 != endpoints[j].P90 {
+			return endpoints[i].P90 > endpoints[j].P90
+		}
+		return endpoints[i].Route < endpoints[j].Route
+	})
+	return endpoints
+}
+
+// Percentile returns the nearest-rank p-th percentile of sorted, which must
+// not be empty.
+func Percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+func top(requests []*types.RequestLog, key func(*types.RequestLog) int64) []*types.RequestLog {
+	sorted := slices.Clone(requests)
+	sort.SliceStable(sorted, func(i, j int) bool { return key(sorted[i]) > key(sorted[j]) })
+	return sorted[:min(TopN, len(sorted))]
+}
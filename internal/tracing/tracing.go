@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/mtavano/golden-gate/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name identifies Golden Gate's instrumentation in exported spans.
+const Name = "github.com/mtavano/golden-gate"
+
+// Setup installs the global tracer provider and W3C propagators according to
+// cfg. When tracing is disabled the globals are left as no-ops, so spans are
+// never recorded and trace headers pass through untouched. The returned
+// function flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	if !cfg.TracingEnabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	// The endpoint is the collector base URL unless it already names a path.
+	endpoint := cfg.Tracing.Endpoint
+	if u, err := url.Parse(endpoint); err == nil && strings.Trim(u.Path, "/") == "" {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}
+	if len(cfg.Tracing.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Tracing.Headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := 1.0
+	if cfg.Tracing.SampleRatio != nil {
+		ratio = *cfg.Tracing.SampleRatio
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.Tracing.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns Golden Gate's tracer from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(Name)
+}
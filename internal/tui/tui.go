@@ -0,0 +1,289 @@
+// Package tui shows the captured traffic in the terminal, for when opening
+// the web dashboard is awkward, like over SSH.
+package tui
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mtavano/golden-gate/internal/filter"
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+// refreshInterval bounds how often the list is rebuilt under load.
+const refreshInterval = 100 * time.Millisecond
+
+// Run shows the requests in store until the user quits or ctx is done.
+// title is shown in the header, e.g. the listen address.
+func Run(ctx context.Context, store *types.RequestStore, title string) error {
+	events, unsubscribe := store.Subscribe(256)
+	defer unsubscribe()
+
+	m := &model{store: store, events: events, title: title}
+	m.refresh()
+
+	_, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	if errors.Is(err, tea.ErrProgramKilled) && ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// storeMsg reports changes to the store, batched over refreshInterval.
+type storeMsg struct {
+	added   int
+	updated []string
+}
+
+type model struct {
+	store  *types.RequestStore
+	events <-chan types.StoreEvent
+	title  string
+
+	width, height int
+
+	// requests are the matching requests, newest first.
+	requests []*types.RequestLog
+	total    int
+	cursor   int
+	offset   int
+	paused   bool
+	queued   int
+
+	filter     *filter.Filter
+	filterText string
+	editing    bool
+	input      string
+	inputErr   string
+
+	// detail is the request shown full screen, if any.
+	detail *types.RequestLog
+	lines  []string
+	scroll int
+}
+
+func (m *model) Init() tea.Cmd {
+	return m.wait()
+}
+
+// wait receives the next store events.
+func (m *model) wait() tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-m.events
+		if !ok {
+			return nil
+		}
+		time.Sleep(refreshInterval)
+
+		var msg storeMsg
+		for {
+			if event.Type == types.EventAdd {
+				msg.added++
+			} else {
+				msg.updated = append(msg.updated, event.Request.ID)
+			}
+			select {
+			case event = <-m.events:
+			default:
+				return msg
+			}
+		}
+	}
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.scrollTo(m.cursor)
+		if m.detail != nil {
+			m.showDetail(m.detail)
+		}
+
+	case storeMsg:
+		if m.paused {
+			m.queued += msg.added
+		} else {
+			m.refresh()
+		}
+		if m.detail != nil {
+			for _, id := range msg.updated {
+				if id == m.detail.ID {
+					if req := m.store.GetRequest(id); req != nil {
+						m.showDetail(req)
+					}
+				}
+			}
+		}
+		return m, m.wait()
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		switch {
+		case m.editing:
+			m.editKey(msg)
+		case m.detail != nil:
+			m.detailKey(msg)
+		default:
+			return m, m.listKey(msg)
+		}
+	}
+	return m, nil
+}
+
+func (m *model) listKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "q":
+		return tea.Quit
+	case "up", "k":
+		m.scrollTo(m.cursor - 1)
+	case "down", "j":
+		m.scrollTo(m.cursor + 1)
+	case "pgup", "b":
+		m.scrollTo(m.cursor - m.rows())
+	case "pgdown", "f":
+		m.scrollTo(m.cursor + m.rows())
+	case "home", "g":
+		m.scrollTo(0)
+	case "end", "G":
+		m.scrollTo(len(m.requests) - 1)
+	case "enter", "right", "l":
+		if m.cursor < len(m.requests) {
+			m.showDetail(m.requests[m.cursor])
+		}
+	case "/":
+		m.editing, m.input, m.inputErr = true, m.filterText, ""
+	case "esc":
+		if m.filter != nil {
+			m.filter, m.filterText = nil, ""
+			m.refresh()
+		}
+	case " ", "p":
+		m.paused = !m.paused
+		if !m.paused {
+			m.refresh()
+		}
+	}
+	return nil
+}
+
+func (m *model) detailKey(msg tea.KeyMsg) {
+	page := max(1, m.height-2)
+	switch msg.String() {
+	case "esc", "q", "left", "h", "backspace":
+		m.detail = nil
+	case "up", "k":
+		m.scroll--
+	case "down", "j":
+		m.scroll++
+	case "pgup", "b":
+		m.scroll -= page
+	case "pgdown", "f", " ":
+		m.scroll += page
+	case "home", "g":
+		m.scroll = 0
+	case "end", "G":
+		m.scroll = len(m.lines)
+	case "n", "N":
+		step := 1
+		if msg.String() == "N" {
+			step = -1
+		}
+		m.scrollTo(m.cursor + step)
+		if m.cursor < len(m.requests) {
+			m.showDetail(m.requests[m.cursor])
+			m.scroll = 0
+		}
+	}
+	m.scroll = max(0, min(m.scroll, len(m.lines)-page))
+}
+
+func (m *model) editKey(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		f, err := filter.ParseTerms(m.input)
+		if err != nil {
+			m.inputErr = err.Error()
+			return
+		}
+		m.editing, m.filterText, m.filter = false, m.input, f
+		if !f.Active() {
+			m.filter = nil
+		}
+		m.refresh()
+	case tea.KeyEsc:
+		m.editing = false
+	case tea.KeyBackspace:
+		if r := []rune(m.input); len(r) > 0 {
+			m.input = string(r[:len(r)-1])
+		}
+	case tea.KeyCtrlU:
+		m.input = ""
+	case tea.KeySpace:
+		m.input += " "
+	case tea.KeyRunes:
+		m.input += string(msg.Runes)
+	}
+	m.inputErr = ""
+}
+
+// refresh rebuilds the list from the store. The selected request stays
+// selected, unless it is the newest one: then the selection follows new
+// requests.
+func (m *model) refresh() {
+	var selected string
+	if m.cursor > 0 && m.cursor < len(m.requests) {
+		selected = m.requests[m.cursor].ID
+	}
+
+	all := m.store.GetRequests()
+	m.total = len(all)
+	m.queued = 0
+	m.requests = make([]*types.RequestLog, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		if m.filter == nil || m.filter.Match(all[i]) {
+			m.requests = append(m.requests, all[i])
+		}
+	}
+
+	cursor := 0
+	if selected != "" {
+		cursor = m.cursor
+		for i, req := range m.requests {
+			if req.ID == selected {
+				m.offset += i - m.cursor
+				cursor = i
+				break
+			}
+		}
+	}
+	m.scrollTo(cursor)
+}
+
+// scrollTo moves the cursor to row i, scrolling the list to keep it visible.
+func (m *model) scrollTo(i int) {
+	m.cursor = max(0, min(i, len(m.requests)-1))
+	rows := m.rows()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+	m.offset = max(0, min(m.offset, len(m.requests)-rows))
+}
+
+// rows is the number of requests that fit on screen, below the two header
+// lines and above the footer.
+func (m *model) rows() int {
+	return max(1, m.height-3)
+}
+
+func (m *model) showDetail(req *types.RequestLog) {
+	m.detail = req
+	m.lines = detailLines(req, m.width)
+}
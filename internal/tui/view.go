@@ -0,0 +1,277 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+	"github.com/mtavano/golden-gate/internal/types"
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	dimStyle      = lipgloss.NewStyle().Faint(true)
+	headingStyle  = lipgloss.NewStyle().Bold(true).Underline(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	statusStyles  = map[int]lipgloss.Style{
+		2: lipgloss.NewStyle().Foreground(lipgloss.Color("2")),
+		3: lipgloss.NewStyle().Foreground(lipgloss.Color("6")),
+		4: lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+		5: errorStyle,
+	}
+	pendingStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+)
+
+const (
+	listHelp   = "↑/↓ move  enter details  / filter  esc clear filter  space pause  q quit"
+	detailHelp = "↑/↓ scroll  n/N next/previous request  esc back  ctrl+c quit"
+	filterHelp = "name=value terms: service method status path from to header q; other words match the path"
+)
+
+func (m *model) View() string {
+	if m.width == 0 {
+		return ""
+	}
+	if m.detail != nil {
+		return m.detailView()
+	}
+	return m.listView()
+}
+
+func (m *model) listView() string {
+	var b strings.Builder
+
+	state := "Live"
+	if m.paused {
+		state = "Paused"
+		if m.queued > 0 {
+			state += fmt.Sprintf(" (%d new)", m.queued)
+		}
+	}
+	header := fmt.Sprintf("%s  %s  %d/%d requests  %s", titleStyle.Render("Golden Gate"), m.title, len(m.requests), m.total, state)
+	if m.filterText != "" {
+		header += "  filter: " + m.filterText
+	}
+	b.WriteString(fit(header, m.width) + "\n")
+	b.WriteString(dimStyle.Render(fit(fmt.Sprintf("  %-8s  %-7s  %-6s  %9s  %-12s  %s", "TIME", "METHOD", "STATUS", "LATENCY", "SERVICE", "PATH"), m.width)) + "\n")
+
+	rows := m.rows()
+	for i := m.offset; i < m.offset+rows; i++ {
+		if i < len(m.requests) {
+			b.WriteString(m.row(m.requests[i], i == m.cursor))
+		}
+		b.WriteString("\n")
+	}
+
+	switch {
+	case m.editing:
+		input := "/" + m.input + "█"
+		if m.inputErr != "" {
+			input += "  " + errorStyle.Render(m.inputErr)
+		} else {
+			input += "  " + dimStyle.Render(filterHelp)
+		}
+		b.WriteString(fit(input, m.width))
+	case len(m.requests) == 0 && m.filter != nil:
+		b.WriteString(dimStyle.Render(fit("No captured requests match the filter. "+listHelp, m.width)))
+	default:
+		b.WriteString(dimStyle.Render(fit(listHelp, m.width)))
+	}
+	return b.String()
+}
+
+// row renders one request. Columns are padded before styling, so escape
+// codes do not affect the alignment.
+func (m *model) row(req *types.RequestLog, selected bool) string {
+	status, style := statusText(req)
+	latency := ""
+	if !req.Pending {
+		latency = formatDuration(req.Duration)
+	}
+
+	prefix := "  "
+	if selected {
+		prefix = "> "
+	}
+	left := fmt.Sprintf("%s%-8s  %-7s  ", prefix, req.Timestamp.Local().Format("15:04:05"), req.Method)
+	right := fmt.Sprintf("  %9s  %s  ", latency, runewidth.FillRight(runewidth.Truncate(req.Service, 12, "…"), 12))
+	rest := max(0, m.width-runewidth.StringWidth(left)-6-runewidth.StringWidth(right))
+	path := runewidth.Truncate(displayPath(req), rest, "…")
+
+	if selected {
+		return selectedStyle.Render(runewidth.FillRight(left+fmt.Sprintf("%-6s", status)+right+path, m.width))
+	}
+	return left + style.Render(fmt.Sprintf("%-6s", status)) + right + path
+}
+
+func (m *model) detailView() string {
+	var b strings.Builder
+
+	req := m.detail
+	status, style := statusText(req)
+	header := fmt.Sprintf("%s %s  %s", titleStyle.Render(req.Method), displayPath(req), style.Render(status))
+	b.WriteString(fit(header, m.width) + "\n")
+
+	page := max(1, m.height-2)
+	end := min(m.scroll+page, len(m.lines))
+	for i := m.scroll; i < m.scroll+page; i++ {
+		if i < end {
+			b.WriteString(m.lines[i])
+		}
+		b.WriteString("\n")
+	}
+
+	help := detailHelp
+	if len(m.lines) > page {
+		help = fmt.Sprintf("%d-%d of %d lines  %s", m.scroll+1, end, len(m.lines), help)
+	}
+	b.WriteString(dimStyle.Render(fit(help, m.width)))
+	return b.String()
+}
+
+// detailLines renders req as lines wrapped to width.
+func detailLines(req *types.RequestLog, width int) []string {
+	var b strings.Builder
+
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%-12s %s\n", name, value)
+		}
+	}
+	field("ID", req.ID)
+	field("Service", req.Service)
+	field("Received", req.Timestamp.Local().Format("2006-01-02 15:04:05.000 MST"))
+	if !req.Pending {
+		field("Total", formatDuration(req.Duration))
+	}
+	if req.UpstreamDuration > 0 {
+		field("Upstream", formatDuration(req.UpstreamDuration))
+	}
+	field("Incoming", req.IncomingURL)
+	field("Upstream URL", req.URL)
+	field("Trace", req.TraceID)
+	field("Operation", req.Operation)
+	if req.Error != "" {
+		field("Error", errorStyle.Render(req.Error))
+	}
+	for _, violation := range req.Violations {
+		field("Violation", errorStyle.Render("["+violation.Scope+"] "+violation.Message))
+	}
+
+	section := func(title string) {
+		b.WriteString("\n" + headingStyle.Render(title) + "\n")
+	}
+	section("Request headers")
+	writeHeaders(&b, req.Headers)
+	if len(req.Query) > 0 {
+		section("Query parameters")
+		writeHeaders(&b, req.Query)
+	}
+	section(fmt.Sprintf("Request body (%s)", formatSize(len(req.Body))))
+	b.WriteString(formatBody(req.Body) + "\n")
+
+	if req.Response != nil {
+		section(fmt.Sprintf("Response %d %s", req.Response.StatusCode, http.StatusText(req.Response.StatusCode)))
+		writeHeaders(&b, req.Response.Headers)
+		section(fmt.Sprintf("Response body (%s)", formatSize(len(req.Response.Body))))
+		b.WriteString(formatBody(req.Response.Body) + "\n")
+	}
+
+	text := strings.TrimRight(b.String(), "\n")
+	if width > 0 {
+		text = ansi.Hardwrap(text, width, true)
+	}
+	return strings.Split(text, "\n")
+}
+
+func writeHeaders(b *strings.Builder, headers map[string][]string) {
+	if len(headers) == 0 {
+		b.WriteString(dimStyle.Render("(none)") + "\n")
+		return
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range headers[name] {
+			fmt.Fprintf(b, "%s: %s\n", name, value)
+		}
+	}
+}
+
+// formatBody pretty prints JSON bodies and shows other text as is.
+func formatBody(body []byte) string {
+	if len(body) == 0 {
+		return dimStyle.Render("(empty)")
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err == nil {
+		return pretty.String()
+	}
+	if utf8.Valid(body) {
+		return strings.TrimRight(string(body), "\n")
+	}
+	return dimStyle.Render("[binary data]")
+}
+
+func statusText(req *types.RequestLog) (string, lipgloss.Style) {
+	switch {
+	case req.Pending:
+		return "…", pendingStyle
+	case req.Error != "":
+		return "ERR", errorStyle
+	case req.Response != nil:
+		return fmt.Sprint(req.Response.StatusCode), statusStyles[req.Response.StatusCode/100]
+	}
+	return "-", lipgloss.NewStyle()
+}
+
+// displayPath is the path and query the client requested.
+func displayPath(req *types.RequestLog) string {
+	raw := req.IncomingURL
+	if raw == "" {
+		raw = req.URL
+	}
+	if u, err := url.Parse(raw); err == nil {
+		return u.RequestURI()
+	}
+	return raw
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < time.Second:
+		return d.Round(100 * time.Microsecond).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+func formatSize(n int) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KiB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1024*1024))
+}
+
+// fit truncates s, which may contain escape codes, to width cells.
+func fit(s string, width int) string {
+	return ansi.Truncate(s, width, "…")
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var (
+	idMu       sync.Mutex
+	idLastMs   uint64
+	idLastRand [10]byte
+)
+
+// NewID returns a ULID: 26 characters that sort by creation time. IDs made
+// within the same millisecond increment the random part, so they still sort
+// in creation order.
+func NewID() string {
+	idMu.Lock()
+	ms := uint64(time.Now().UnixMilli())
+	if ms == idLastMs {
+		for i := len(idLastRand) - 1; i >= 0; i-- {
+			idLastRand[i]++
+			if idLastRand[i] != 0 {
+				break
+			}
+		}
+	} else {
+		idLastMs = ms
+		rand.Read(idLastRand[:])
+	}
+
+	var id [16]byte
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	copy(id[6:], idLastRand[:])
+	idMu.Unlock()
+
+	// 128 bits in 26 base32 characters; the first one holds 3 bits.
+	out := make([]byte, 26)
+	for i := range out {
+		bit := i*5 - 2
+		var v byte
+		for j := 0; j < 5; j++ {
+			b := bit + j
+			v <<= 1
+			if b >= 0 && id[b/8]&(0x80>>(b%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockford[v]
+	}
+	return string(out)
+}
@@ -1,47 +1,203 @@
 package types
 
 import (
+	"sync"
 	"time"
 )
 
 type RequestLog struct {
-	ID        string
-	Timestamp time.Time
-	Duration  time.Duration
-	Method    string
-	Path      string
-	URL       string
-	Headers   map[string][]string
-	Query     map[string][]string
-	Body      []byte
-	Response  *ResponseLog
+	// ID is a sortable unique id, see NewID.
+	ID string `json:"id"`
+	// Seq is assigned by the RequestStore in capture order.
+	Seq              uint64              `json:"seq,omitempty"`
+	TraceID          string              `json:"trace_id,omitempty"`
+	Service          string              `json:"service,omitempty"`
+	Timestamp        time.Time           `json:"timestamp"`
+	Duration         time.Duration       `json:"duration"`
+	UpstreamDuration time.Duration       `json:"upstream_duration,omitempty"`
+	Method           string              `json:"method"`
+	Path             string              `json:"path,omitempty"`
+	IncomingURL      string              `json:"incoming_url,omitempty"`
+	URL              string              `json:"url"`
+	Headers          map[string][]string `json:"headers,omitempty"`
+	Query            map[string][]string `json:"query,omitempty"`
+	Body             []byte              `json:"body,omitempty"`
+	Response         *ResponseLog        `json:"response,omitempty"`
+	// Operation is the OpenAPI operation the request matched, e.g.
+	// "GET /markets/{id}", when the service has a contract.
+	Operation  string              `json:"operation,omitempty"`
+	Violations []ContractViolation `json:"violations,omitempty"`
+	// Pending is set while the upstream has not answered yet.
+	Pending bool `json:"pending,omitempty"`
+	// Error is why the upstream round trip failed, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+// ContractViolation is a difference between an exchange and the OpenAPI
+// document of its service.
+type ContractViolation struct {
+	Scope   string `json:"scope"` // route, request or response
+	Message string `json:"message"`
 }
 
 type ResponseLog struct {
-	StatusCode int
-	Headers    map[string][]string
-	Body       []byte
+	StatusCode int                 `json:"status_code"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       []byte              `json:"body,omitempty"`
 }
 
 type RequestStore struct {
+	mu       sync.RWMutex
 	requests []*RequestLog
 	maxSize  int
+	evicted  uint64
+	seq      uint64
+	// subscribers maps each subscription to its count of dropped events.
+	subscribers map[chan StoreEvent]*uint64
 }
 
+// StoreEvent tells subscribers that a request was captured (EventAdd) or
+// that a pending one completed (EventUpdate).
+type StoreEvent struct {
+	Type    string
+	Request *RequestLog
+}
+
+const (
+	EventAdd    = "add"
+	EventUpdate = "update"
+)
+
 func NewRequestStore(maxSize int) *RequestStore {
 	return &RequestStore{
-		requests: make([]*RequestLog, 0),
-		maxSize:  maxSize,
+		requests:    make([]*RequestLog, 0),
+		maxSize:     maxSize,
+		subscribers: map[chan StoreEvent]*uint64{},
 	}
 }
 
+// AddRequest stores req and assigns its Seq, and an ID if it has none.
+// Stored requests must not be modified afterwards; use Complete to replace a
+// pending one.
 func (rs *RequestStore) AddRequest(req *RequestLog) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if req.ID == "" {
+		req.ID = NewID()
+	}
 	if len(rs.requests) >= rs.maxSize {
 		rs.requests = rs.requests[1:]
+		rs.evicted++
 	}
+	rs.seq++
+	req.Seq = rs.seq
 	rs.requests = append(rs.requests, req)
+	rs.publish(StoreEvent{Type: EventAdd, Request: req})
+}
+
+// Complete replaces the pending request with its final version, which must
+// carry the same Seq. Nothing is stored if pending was evicted meanwhile.
+func (rs *RequestStore) Complete(pending, final *RequestLog) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for i := len(rs.requests) - 1; i >= 0; i-- {
+		if rs.requests[i] == pending {
+			rs.requests[i] = final
+			rs.publish(StoreEvent{Type: EventUpdate, Request: final})
+			return
+		}
+	}
+}
+
+// GetRequest returns the stored request with the given ID, or nil.
+func (rs *RequestStore) GetRequest(id string) *RequestLog {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	for i := len(rs.requests) - 1; i >= 0; i-- {
+		if rs.requests[i].ID == id {
+			return rs.requests[i]
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving every change to the store. Events
+// are dropped for subscribers that fall more than buffer events behind, see
+// Dropped. The returned func unsubscribes and must be called.
+func (rs *RequestStore) Subscribe(buffer int) (<-chan StoreEvent, func()) {
+	ch := make(chan StoreEvent, buffer)
+
+	rs.mu.Lock()
+	rs.subscribers[ch] = new(uint64)
+	rs.mu.Unlock()
+
+	return ch, func() {
+		rs.mu.Lock()
+		delete(rs.subscribers, ch)
+		rs.mu.Unlock()
+	}
+}
+
+// Dropped returns how many events were dropped so far for the subscription
+// of events because it fell behind. Subscribers that must not miss a capture
+// look at the stored requests again when it grows.
+func (rs *RequestStore) Dropped(events <-chan StoreEvent) uint64 {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	for ch, dropped := range rs.subscribers {
+		if (<-chan StoreEvent)(ch) == events {
+			return *dropped
+		}
+	}
+	return 0
 }
 
+// publish must be called with rs.mu held.
+func (rs *RequestStore) publish(event StoreEvent) {
+	for ch, dropped := range rs.subscribers {
+		select {
+		case ch <- event:
+		default:
+			*dropped++
+		}
+	}
+}
+
+// GetRequests returns a snapshot of the stored requests, oldest first.
 func (rs *RequestStore) GetRequests() []*RequestLog {
-	return rs.requests
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	requests := make([]*RequestLog, len(rs.requests))
+	copy(requests, rs.requests)
+	return requests
+}
+
+// Len returns the number of stored requests.
+func (rs *RequestStore) Len() int {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	return len(rs.requests)
+}
+
+// LastSeq returns the Seq of the latest captured request, or 0 if none was
+// captured yet.
+func (rs *RequestStore) LastSeq() uint64 {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	return rs.seq
+}
+
+// Evicted returns how many requests were dropped to stay within maxSize.
+func (rs *RequestStore) Evicted() uint64 {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	return rs.evicted
 }